@@ -0,0 +1,343 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// Operations that APIAuthConfig.Allow can restrict.
+const (
+	opCreate    = "create"
+	opUpdate    = "update"
+	opDelete    = "delete"
+	opRead      = "read"
+	opCancel    = "cancel"
+	opTerminate = "terminate"
+)
+
+var operations = map[string]bool{opCreate: true, opUpdate: true, opDelete: true, opRead: true, opCancel: true, opTerminate: true}
+
+const (
+	// callerContextKey holds the authenticated caller in the gin context.
+	callerContextKey = "caller"
+	// callerMemoKey holds it in the memo of the sagas it starts.
+	callerMemoKey = "caller"
+)
+
+// caller identifies the client of a request. It is recorded in the memo of
+// the sagas it starts.
+type caller struct {
+	ID     string `json:"id"`
+	Scheme string `json:"scheme"` // api_key or jwt
+}
+
+// callerAuthenticator identifies callers by one credential scheme. ok is
+// false when the request carries no credentials for the scheme.
+type callerAuthenticator interface {
+	authenticate(r *http.Request) (c caller, ok bool, err error)
+}
+
+// apiKeyAuthenticator accepts static keys sent in a header.
+type apiKeyAuthenticator struct {
+	header string
+	keys   map[string]string // client id -> key
+}
+
+func (a apiKeyAuthenticator) authenticate(r *http.Request) (caller, bool, error) {
+	key := r.Header.Get(a.header)
+	if key == "" {
+		return caller{}, false, nil
+	}
+	// Compare against every key so timing does not reveal which one matched.
+	var id string
+	for client, k := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			id = client
+		}
+	}
+	if id == "" {
+		return caller{}, true, errors.New("invalid api key")
+	}
+	return caller{ID: id, Scheme: "api_key"}, true, nil
+}
+
+// jwtAuthenticator accepts bearer JWTs signed by a key of a local JWKS.
+type jwtAuthenticator struct {
+	keys        map[string]any // kid -> public key
+	clientClaim string
+	parser      *jwt.Parser
+}
+
+func newJWTAuthenticator(cfg config.APIAuthConfig) (*jwtAuthenticator, error) {
+	keys, err := loadJWKS(cfg.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	return &jwtAuthenticator{keys: keys, clientClaim: cfg.JWTClientClaim, parser: jwt.NewParser(opts...)}, nil
+}
+
+func (a *jwtAuthenticator) authenticate(r *http.Request) (caller, bool, error) {
+	raw, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return caller{}, false, nil
+	}
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.key); err != nil {
+		return caller{}, true, err
+	}
+	id, _ := claims[a.clientClaim].(string)
+	if id == "" {
+		return caller{}, true, fmt.Errorf("token has no %s claim", a.clientClaim)
+	}
+	return caller{ID: id, Scheme: "jwt"}, true, nil
+}
+
+// key picks the verification key named by the token's kid; tokens without
+// one are accepted only when the JWKS holds a single key.
+func (a *jwtAuthenticator) key(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			return k, nil
+		}
+	}
+	if k, ok := a.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// jwk is the subset of RFC 7517 needed for RSA and EC public keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the signing keys of a JWKS file, skipping encryption keys.
+func loadJWKS(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks %s has no signing keys", path)
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// accessControl authenticates and authorizes API requests.
+type accessControl struct {
+	authenticators []callerAuthenticator
+	allow          map[string]map[string]bool // operation -> client ids; absent means any caller
+}
+
+func newAccessControl(cfg config.APIAuthConfig) (*accessControl, error) {
+	ac := &accessControl{allow: map[string]map[string]bool{}}
+	if len(cfg.APIKeys) > 0 {
+		ac.authenticators = append(ac.authenticators, apiKeyAuthenticator{header: cfg.APIKeyHeader, keys: cfg.APIKeys})
+	}
+	if cfg.JWKSFile != "" {
+		a, err := newJWTAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		ac.authenticators = append(ac.authenticators, a)
+	}
+	for op, clients := range cfg.Allow {
+		if !operations[op] {
+			return nil, fmt.Errorf("API_AUTH_ALLOW: unknown operation %q", op)
+		}
+		ac.allow[op] = map[string]bool{}
+		for _, id := range strings.Split(clients, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ac.allow[op][id] = true
+			}
+		}
+	}
+	if len(ac.allow) > 0 && len(ac.authenticators) == 0 {
+		return nil, errors.New("API_AUTH_ALLOW needs API_AUTH_API_KEYS or API_AUTH_JWKS_FILE")
+	}
+	return ac, nil
+}
+
+// authenticate identifies the caller with the first scheme its request
+// carries credentials for. It lets every request through when no scheme is
+// configured.
+func (ac *accessControl) authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(ac.authenticators) == 0 {
+			c.Next()
+			return
+		}
+		for _, a := range ac.authenticators {
+			who, ok, err := a.authenticate(c.Request)
+			if !ok {
+				continue
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated: " + err.Error()})
+				return
+			}
+			c.Set(callerContextKey, who)
+			c.Next()
+			return
+		}
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	}
+}
+
+// authorize admits the caller to op if the Allow rules permit it.
+func (ac *accessControl) authorize(op string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients, restricted := ac.allow[op]
+		if restricted && !clients[callerOf(c).ID] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("not allowed to %s sagas", op)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ownerOnly admits an authenticated caller to the saga it started, so clients
+// sharing the API cannot cancel each other's sagas. Sagas started while the
+// API was open have no owner and requests with the admin token act on any
+// saga; with the API open, access is decided by the operation rules alone.
+func (ac *accessControl) ownerOnly(cfg config.Config, cl client.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		who := callerOf(c)
+		if who.ID == "" || isAdmin(c, cfg) {
+			c.Next()
+			return
+		}
+		var owner caller
+		ok, err := memoValue(c, cl, c.Param("id"), "", callerMemoKey, &owner)
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "saga not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if ok && owner.ID != who.ID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "saga was started by another client"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requester describes the client of a request for audit logs.
+func requester(c *gin.Context, cfg config.Config) string {
+	who := "anonymous client"
+	if v := callerOf(c); v.ID != "" {
+		who = fmt.Sprintf("client %q (%s)", v.ID, v.Scheme)
+	}
+	if isAdmin(c, cfg) {
+		who += " with the admin token"
+	}
+	return who
+}
+
+// callerOf returns the authenticated caller, zero when the API is open.
+func callerOf(c *gin.Context) caller {
+	who, _ := c.Get(callerContextKey)
+	v, _ := who.(caller)
+	return v
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+)
+
+// writeJWKS stores the public half of key as a one-key JWKS.
+func writeJWKS(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	set := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "k1", "use": "sig",
+		"n": enc.EncodeToString(key.N.Bytes()),
+		"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	b, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "k1"
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func Test_AccessControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.APIAuth = config.APIAuthConfig{
+		APIKeys:        map[string]string{"billing": "b-key", "ops": "o-key"},
+		APIKeyHeader:   "X-API-Key",
+		JWKSFile:       writeJWKS(t, key),
+		JWTIssuer:      "https://idp.test",
+		JWTClientClaim: "sub",
+		Allow:          map[string]string{"delete": "ops, svc-reporting"},
+	}
+
+	cl := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("saga-1").Maybe()
+	run.On("GetRunID").Return("run-1").Maybe()
+	var memos []map[string]any
+	cl.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { memos = append(memos, args.Get(1).(client.StartWorkflowOptions).Memo) }).
+		Return(run, nil)
+	cl.On("CompleteActivity", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(serviceerror.NewNotFound("gone"))
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := signToken(t, key, jwt.MapClaims{"sub": "svc-reporting", "iss": "https://idp.test", "exp": time.Now().Add(time.Hour).Unix()})
+	expired := signToken(t, key, jwt.MapClaims{"sub": "svc-reporting", "iss": "https://idp.test", "exp": time.Now().Add(-time.Hour).Unix()})
+	foreign := signToken(t, key, jwt.MapClaims{"sub": "svc-reporting", "iss": "https://other.test", "exp": time.Now().Add(time.Hour).Unix()})
+	deleteBody := `{"id1": "a", "id2": "b", "id3": "c"}`
+	callback, err := activities.SealTaskToken(cfg.CallbackSecret, []byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, path, body string
+		header           [2]string
+		want             int
+	}{
+		{"no credentials", "/create", `{}`, [2]string{}, http.StatusUnauthorized},
+		{"unknown api key", "/create", `{}`, [2]string{"X-API-Key", "nope"}, http.StatusUnauthorized},
+		{"api key", "/create", `{}`, [2]string{"X-API-Key", "b-key"}, http.StatusOK},
+		{"api key not allowed to delete", "/delete", deleteBody, [2]string{"X-API-Key", "b-key"}, http.StatusForbidden},
+		{"api key allowed to delete", "/delete", deleteBody, [2]string{"X-API-Key", "o-key"}, http.StatusOK},
+		{"jwt allowed to delete", "/delete", deleteBody, [2]string{"Authorization", "Bearer " + valid}, http.StatusOK},
+		{"expired jwt", "/create", `{}`, [2]string{"Authorization", "Bearer " + expired}, http.StatusUnauthorized},
+		{"jwt from another issuer", "/create", `{}`, [2]string{"Authorization", "Bearer " + foreign}, http.StatusUnauthorized},
+		{"callbacks are exempt", activities.CallbackPath + callback, `{}`, [2]string{}, http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.header[0] != "" {
+			req.Header.Set(tc.header[0], tc.header[1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.want, w.Code, w.Body)
+		}
+	}
+
+	want := []caller{{ID: "billing", Scheme: "api_key"}, {ID: "ops", Scheme: "api_key"}, {ID: "svc-reporting", Scheme: "jwt"}}
+	if len(memos) != len(want) {
+		t.Fatalf("expected %d starts, got %d", len(want), len(memos))
+	}
+	for i, m := range memos {
+		if m[callerMemoKey] != want[i] {
+			t.Fatalf("start %d: expected caller %+v in memo, got %v", i, want[i], m)
+		}
+	}
+}
+
+func Test_AccessControl_AllowNeedsAuthentication(t *testing.T) {
+	if _, err := newAccessControl(config.APIAuthConfig{Allow: map[string]string{"delete": "ops"}}); err == nil {
+		t.Fatal("expected an error for rules without authentication")
+	}
+	if _, err := newAccessControl(config.APIAuthConfig{APIKeys: map[string]string{"ops": "k"}, Allow: map[string]string{"purge": "ops"}}); err == nil {
+		t.Fatal("expected an error for an unknown operation")
+	}
+}
+
+func Test_AccessControl_OwnerOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig()
+	cfg.AdminToken = "admin"
+	cfg.APIAuth = config.APIAuthConfig{
+		APIKeys:      map[string]string{"billing": "b-key", "ops": "o-key"},
+		APIKeyHeader: "X-API-Key",
+	}
+
+	cl := mocks.NewClient(t)
+	owner, err := converter.GetDefaultDataConverter().ToPayload(caller{ID: "billing", Scheme: "api_key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.On("DescribeWorkflowExecution", mock.Anything, "saga-1", "").Return(&workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{Memo: &commonpb.Memo{Fields: map[string]*commonpb.Payload{callerMemoKey: owner}}},
+	}, nil)
+	cl.On("DescribeWorkflowExecution", mock.Anything, "open-1", "").Return(&workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{},
+	}, nil)
+	cl.On("DescribeWorkflowExecution", mock.Anything, "gone-1", "").Return(nil, serviceerror.NewNotFound("gone"))
+	var cancelled []string
+	cl.On("CancelWorkflow", mock.Anything, mock.Anything, "").
+		Run(func(args mock.Arguments) { cancelled = append(cancelled, args.String(1)) }).
+		Return(nil)
+	cl.On("TerminateWorkflow", mock.Anything, "saga-1", "", "stuck", caller{ID: "ops", Scheme: "api_key"}).Return(nil).Once()
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, path, key, admin string
+		want                   int
+	}{
+		{"owner cancels", "/sagas/saga-1/cancel", "b-key", "", http.StatusAccepted},
+		{"other client cancels", "/sagas/saga-1/cancel", "o-key", "", http.StatusForbidden},
+		{"other client with the admin token", "/sagas/saga-1/cancel", "o-key", "admin", http.StatusAccepted},
+		{"saga without an owner", "/sagas/open-1/cancel", "o-key", "", http.StatusAccepted},
+		{"unknown saga", "/sagas/gone-1/cancel", "o-key", "", http.StatusNotFound},
+		{"terminate records the caller", "/sagas/saga-1/terminate", "o-key", "admin", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(`{"reason": "stuck"}`))
+		req.Header.Set("X-API-Key", tc.key)
+		if tc.admin != "" {
+			req.Header.Set(adminTokenHeader, tc.admin)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.want, w.Code, w.Body)
+		}
+	}
+	if want := []string{"saga-1", "saga-1", "open-1"}; !reflect.DeepEqual(cancelled, want) {
+		t.Fatalf("expected cancellations %v, got %v", want, cancelled)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// Saga event names streamed by GET /sagas/:id/events.
+const (
+	eventStepStarted          = "step-started"
+	eventStepCompleted        = "step-completed"
+	eventStepFailed           = "step-failed"
+	eventCompensationStarted  = "compensation-started"
+	eventCompensationFinished = "compensation-finished"
+	eventSagaCompleted        = "saga-completed"
+	eventSagaFailed           = "saga-failed"
+	eventSagaCanceled         = "saga-canceled"
+	eventSagaTerminated       = "saga-terminated"
+	eventSagaTimedOut         = "saga-timed-out"
+)
+
+// sagaEvent is the data of one streamed event. ID is the history event id,
+// also sent as the SSE id so clients can resume with Last-Event-ID.
+type sagaEvent struct {
+	ID      int64                  `json:"id"`
+	Time    time.Time              `json:"time"`
+	Step    string                 `json:"step,omitempty"`
+	Service string                 `json:"service,omitempty"`
+	Status  string                 `json:"status,omitempty"`
+	Result  *activities.StepResult `json:"result,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// scheduledActivity remembers what a scheduled activity was for, so its
+// completion events can be attributed.
+type scheduledActivity struct {
+	step, service string
+	compensation  bool
+}
+
+// historyTranslator turns workflow history events into saga events.
+type historyTranslator struct {
+	scheduled map[int64]scheduledActivity
+	baseURLs  map[string]string // service -> base URL, for legacy rollbacks
+	completed []string          // services of the completed steps, in order
+}
+
+func newHistoryTranslator(cfg config.Config) *historyTranslator {
+	h := &historyTranslator{scheduled: map[int64]scheduledActivity{}, baseURLs: map[string]string{}}
+	for _, service := range []string{"api1", "api2", "api3"} {
+		h.baseURLs[service] = strings.TrimRight(cfg.BaseURL(service), "/")
+	}
+	return h
+}
+
+// rollbackService returns the service a rollback activity compensates.
+// RollbackService names it; legacy Rollback activities only carry the base
+// URL, which services may share, so it is matched against the latest
+// completed step not yet compensated.
+func (h *historyTranslator) rollbackService(typ string, input []*commonpb.Payload) string {
+	var arg string
+	if len(input) > 0 {
+		_ = converter.GetDefaultDataConverter().FromPayload(input[0], &arg)
+	}
+	if typ != "Rollback" {
+		return arg
+	}
+	baseURL := strings.TrimRight(arg, "/")
+	for i := len(h.completed) - 1; i >= 0; i-- {
+		if service := h.completed[i]; h.baseURLs[service] == baseURL {
+			h.completed = append(h.completed[:i], h.completed[i+1:]...)
+			return service
+		}
+	}
+	for _, service := range []string{"api1", "api2", "api3"} {
+		if h.baseURLs[service] == baseURL {
+			return service
+		}
+	}
+	return ""
+}
+
+// translate returns the saga event for e, if any. terminal reports whether
+// the event closes the saga.
+func (h *historyTranslator) translate(e *historypb.HistoryEvent) (name string, ev sagaEvent, ok, terminal bool) {
+	ev = sagaEvent{ID: e.GetEventId(), Time: e.GetEventTime().AsTime()}
+	dc := converter.GetDefaultDataConverter()
+	switch e.GetEventType() {
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+		attrs := e.GetActivityTaskScheduledEventAttributes()
+		var a scheduledActivity
+		if typ := attrs.GetActivityType().GetName(); typ == "RollbackService" || typ == "Rollback" {
+			service := h.rollbackService(typ, attrs.GetInput().GetPayloads())
+			a = scheduledActivity{step: stepForService(service), service: service, compensation: true}
+		} else if strings.HasPrefix(typ, "Step") {
+			step := strings.ToLower(typ)
+			a = scheduledActivity{step: step, service: "api" + strings.TrimPrefix(step, "step")}
+		} else {
+			return "", ev, false, false
+		}
+		h.scheduled[e.GetEventId()] = a
+		ev.Step, ev.Service = a.step, a.service
+		if a.compensation {
+			return eventCompensationStarted, ev, true, false
+		}
+		return eventStepStarted, ev, true, false
+
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+		attrs := e.GetActivityTaskCompletedEventAttributes()
+		a, found := h.scheduled[attrs.GetScheduledEventId()]
+		if !found {
+			return "", ev, false, false
+		}
+		ev.Step, ev.Service = a.step, a.service
+		if a.compensation {
+			ev.Status = "compensated"
+			return eventCompensationFinished, ev, true, false
+		}
+		h.completed = append(h.completed, a.service)
+		var res activities.StepResult
+		if err := dc.FromPayloads(attrs.GetResult(), &res); err == nil {
+			ev.Result = &res
+		}
+		return eventStepCompleted, ev, true, false
+
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED, enumspb.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT, enumspb.EVENT_TYPE_ACTIVITY_TASK_CANCELED:
+		var scheduledID int64
+		switch {
+		case e.GetActivityTaskFailedEventAttributes() != nil:
+			attrs := e.GetActivityTaskFailedEventAttributes()
+			scheduledID, ev.Error = attrs.GetScheduledEventId(), rootFailure(attrs.GetFailure())
+		case e.GetActivityTaskTimedOutEventAttributes() != nil:
+			attrs := e.GetActivityTaskTimedOutEventAttributes()
+			scheduledID, ev.Error = attrs.GetScheduledEventId(), rootFailure(attrs.GetFailure())
+		default:
+			scheduledID, ev.Error = e.GetActivityTaskCanceledEventAttributes().GetScheduledEventId(), "canceled"
+		}
+		a, found := h.scheduled[scheduledID]
+		if !found {
+			return "", ev, false, false
+		}
+		ev.Step, ev.Service = a.step, a.service
+		if a.compensation {
+			ev.Status = "compensation_failed"
+			return eventCompensationFinished, ev, true, false
+		}
+		return eventStepFailed, ev, true, false
+
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED:
+		ev.Status = "completed"
+		return eventSagaCompleted, ev, true, true
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+		ev.Status, ev.Error = "failed", rootFailure(e.GetWorkflowExecutionFailedEventAttributes().GetFailure())
+		return eventSagaFailed, ev, true, true
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CANCELED:
+		ev.Status = "canceled"
+		return eventSagaCanceled, ev, true, true
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED:
+		ev.Status, ev.Error = "terminated", e.GetWorkflowExecutionTerminatedEventAttributes().GetReason()
+		return eventSagaTerminated, ev, true, true
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TIMED_OUT:
+		ev.Status = "timed_out"
+		return eventSagaTimedOut, ev, true, true
+	}
+	return "", ev, false, false
+}
+
+// stepForService maps a downstream service onto the step calling it.
+func stepForService(service string) string {
+	if n := strings.TrimPrefix(service, "api"); n != service {
+		return "step" + n
+	}
+	return ""
+}
+
+// rootFailure returns the message of the innermost cause of f.
+func rootFailure(f *failurepb.Failure) string {
+	for f.GetCause() != nil {
+		f = f.GetCause()
+	}
+	return f.GetMessage()
+}
+
+// parseLastEventID reads the Last-Event-ID header of a reconnecting client;
+// anything unparsable streams from the start.
+func parseLastEventID(s string) int64 {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
+}
+
+// streamSagaEvents long-polls the saga's history and writes saga events as
+// server-sent events until the saga closes or the client goes away. Events
+// up to and including since are skipped.
+func streamSagaEvents(c *gin.Context, cl client.Client, cfg config.Config, workflowID string, since int64) {
+	it := cl.GetWorkflowHistory(c, workflowID, "", true, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	h := newHistoryTranslator(cfg)
+	streaming := false
+	for it.HasNext() {
+		e, err := it.Next()
+		if err != nil {
+			var notFound *serviceerror.NotFound
+			switch {
+			case streaming:
+				c.Render(-1, sse.Event{Event: "error", Data: gin.H{"error": err.Error()}})
+			case errors.As(err, &notFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "saga not found"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		if !streaming {
+			streaming = true
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+		}
+		name, ev, ok, terminal := h.translate(e)
+		if ok && ev.ID > since {
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(ev.ID, 10), Event: name, Data: ev})
+			c.Writer.Flush()
+		}
+		if terminal {
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+)
+
+func testPayloads(t *testing.T, vs ...any) *commonpb.Payloads {
+	t.Helper()
+	p, err := converter.GetDefaultDataConverter().ToPayloads(vs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func scheduledEvent(id int64, typ string, input *commonpb.Payloads) *historypb.HistoryEvent {
+	return &historypb.HistoryEvent{EventId: id, EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED,
+		Attributes: &historypb.HistoryEvent_ActivityTaskScheduledEventAttributes{ActivityTaskScheduledEventAttributes: &historypb.ActivityTaskScheduledEventAttributes{
+			ActivityType: &commonpb.ActivityType{Name: typ}, Input: input,
+		}}}
+}
+
+func completedEvent(id, scheduledID int64, result *commonpb.Payloads) *historypb.HistoryEvent {
+	return &historypb.HistoryEvent{EventId: id, EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED,
+		Attributes: &historypb.HistoryEvent_ActivityTaskCompletedEventAttributes{ActivityTaskCompletedEventAttributes: &historypb.ActivityTaskCompletedEventAttributes{
+			ScheduledEventId: scheduledID, Result: result,
+		}}}
+}
+
+// compensatedHistory is the history of a saga whose step 2 failed after step 1 completed.
+func compensatedHistory(t *testing.T) []*historypb.HistoryEvent {
+	cause := &failurepb.Failure{Message: "activity error", Cause: &failurepb.Failure{Message: "external API error: 500 fail2"}}
+	return []*historypb.HistoryEvent{
+		{EventId: 1, EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED},
+		scheduledEvent(5, "Step1", nil),
+		completedEvent(7, 5, testPayloads(t, activities.StepResult{ResourceID: "a1"})),
+		scheduledEvent(11, "Step2", nil),
+		{EventId: 13, EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskFailedEventAttributes{ActivityTaskFailedEventAttributes: &historypb.ActivityTaskFailedEventAttributes{
+				ScheduledEventId: 11, Failure: cause.Cause,
+			}}},
+		scheduledEvent(17, "RollbackService", testPayloads(t, "api1", "http://x/api1", "a1")),
+		completedEvent(19, 17, nil),
+		{EventId: 23, EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED,
+			Attributes: &historypb.HistoryEvent_WorkflowExecutionFailedEventAttributes{WorkflowExecutionFailedEventAttributes: &historypb.WorkflowExecutionFailedEventAttributes{
+				Failure: &failurepb.Failure{Message: "saga failed", Cause: cause},
+			}}},
+	}
+}
+
+func streamHistory(t *testing.T, events []*historypb.HistoryEvent, lastEventID string) string {
+	t.Helper()
+	it := mocks.NewHistoryEventIterator(t)
+	for _, e := range events {
+		it.On("HasNext").Return(true).Once()
+		it.On("Next").Return(e, nil).Once()
+	}
+	cl := mocks.NewClient(t)
+	cl.On("GetWorkflowHistory", mock.Anything, "saga-1", "", true, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT).Return(it)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	cfg := testConfig()
+	cfg.API1BaseURL, cfg.API2BaseURL, cfg.API3BaseURL = "http://x/shared", "http://x/api2/", "http://x/shared"
+	r.GET("/sagas/:id/events", func(c *gin.Context) { streamSagaEvents(c, cl, cfg, c.Param("id"), parseLastEventID(lastEventID)) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sagas/saga-1/events", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	return w.Body.String()
+}
+
+var eventLine = regexp.MustCompile(`(?m)^event:(\S+)$`)
+
+func Test_StreamSagaEvents(t *testing.T) {
+	body := streamHistory(t, compensatedHistory(t), "")
+	var names []string
+	for _, m := range eventLine.FindAllStringSubmatch(body, -1) {
+		names = append(names, m[1])
+	}
+	want := []string{"step-started", "step-completed", "step-started", "step-failed", "compensation-started", "compensation-finished", "saga-failed"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected events %v\n%s", names, body)
+	}
+	for _, s := range []string{
+		`"step":"step1","service":"api1","result":{"resource_id":"a1"}`,
+		`"error":"external API error: 500 fail2"`,
+		`"step":"step1","service":"api1","status":"compensated"`,
+		"id:23\n",
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("expected %q in stream:\n%s", s, body)
+		}
+	}
+}
+
+func Test_StreamSagaEvents_ResumesAfterLastEventID(t *testing.T) {
+	body := streamHistory(t, compensatedHistory(t), "13")
+	if n := len(eventLine.FindAllString(body, -1)); n != 3 || strings.Contains(body, "id:13\n") {
+		t.Fatalf("expected only events after 13, got %d:\n%s", n, body)
+	}
+}
+
+func Test_StreamSagaEvents_LegacyRollbacks(t *testing.T) {
+	// Before rollbacks named their service, Rollback(baseURL, id) was
+	// scheduled; api1 and api3 share a base URL here.
+	events := []*historypb.HistoryEvent{
+		scheduledEvent(5, "Step1", nil),
+		completedEvent(7, 5, testPayloads(t, activities.StepResult{ResourceID: "a1"})),
+		scheduledEvent(9, "Step2", nil),
+		completedEvent(11, 9, testPayloads(t, activities.StepResult{ResourceID: "b2"})),
+		scheduledEvent(13, "Step3", nil),
+		completedEvent(15, 13, testPayloads(t, activities.StepResult{ResourceID: "c3"})),
+		scheduledEvent(17, "Rollback", testPayloads(t, "http://x/shared", "c3")),
+		completedEvent(19, 17, nil),
+		scheduledEvent(21, "Rollback", testPayloads(t, "http://x/api2", "b2")),
+		completedEvent(23, 21, nil),
+		scheduledEvent(25, "Rollback", testPayloads(t, "http://x/shared/", "a1")),
+		completedEvent(27, 25, nil),
+		{EventId: 29, EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CANCELED},
+	}
+	body := streamHistory(t, events, "15")
+	var compensated []string
+	for _, m := range regexp.MustCompile(`"step":"(\w+)","service":"(\w+)","status":"compensated"`).FindAllStringSubmatch(body, -1) {
+		compensated = append(compensated, m[1]+"/"+m[2])
+	}
+	if want := "step3/api3,step2/api2,step1/api1"; strings.Join(compensated, ",") != want {
+		t.Fatalf("expected compensations %s, got %v:\n%s", want, compensated, body)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// idempotencyKeyHeader names a start request. Scoped to the operation and
+// caller, it becomes the workflow id, so repeating the request returns the
+// saga it started instead of a new one.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// requestMemoKey holds the startRecord in the memo of every saga.
+const requestMemoKey = "request"
+
+// startRecord identifies the start request behind a saga. ID is unique per
+// request and tells a fresh start from one answered with an existing run;
+// Hash covers the request, so a key reused for another request is caught.
+type startRecord struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
+}
+
+func newStartRecord(req startRequest, method string) (startRecord, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return startRecord{}, err
+	}
+	b, err := json.Marshal(struct {
+		Method  string       `json:"method"`
+		Request startRequest `json:"request"`
+	}{method, req})
+	if err != nil {
+		return startRecord{}, err
+	}
+	sum := sha256.Sum256(b)
+	return startRecord{ID: hex.EncodeToString(nonce), Hash: hex.EncodeToString(sum[:])}, nil
+}
+
+// startedRecord reads the startRecord of a run; it is zero for sagas started
+// before records were kept.
+func startedRecord(ctx context.Context, cl client.Client, workflowID, runID string) (startRecord, error) {
+	var rec startRecord
+	_, err := memoValue(ctx, cl, workflowID, runID, requestMemoKey, &rec)
+	return rec, err
+}
+
+var reusePolicies = map[string]enumspb.WorkflowIdReusePolicy{
+	"allow_duplicate":             enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE,
+	"allow_duplicate_failed_only": enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+	"reject_duplicate":            enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	"terminate_if_running":        enumspb.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING,
+}
+
+var conflictPolicies = map[string]enumspb.WorkflowIdConflictPolicy{
+	"fail":               enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	"use_existing":       enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING,
+	"terminate_existing": enumspb.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING,
+}
+
+// idPolicies decide what a start does when its workflow id is taken: reuse
+// applies to closed sagas, conflict to running ones.
+type idPolicies struct {
+	reuse    enumspb.WorkflowIdReusePolicy
+	conflict enumspb.WorkflowIdConflictPolicy
+}
+
+func parseIDPolicies(cfg config.Config) (idPolicies, error) {
+	reuse, ok := reusePolicies[cfg.WorkflowIDReusePolicy]
+	if !ok {
+		return idPolicies{}, fmt.Errorf("unknown WORKFLOW_ID_REUSE_POLICY %q", cfg.WorkflowIDReusePolicy)
+	}
+	conflict, ok := conflictPolicies[cfg.WorkflowIDConflictPolicy]
+	if !ok {
+		return idPolicies{}, fmt.Errorf("unknown WORKFLOW_ID_CONFLICT_POLICY %q", cfg.WorkflowIDConflictPolicy)
+	}
+	// terminate_if_running already decides for running sagas; Temporal
+	// rejects it combined with a conflict policy.
+	if reuse == enumspb.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING {
+		if conflict != enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL {
+			return idPolicies{}, fmt.Errorf("WORKFLOW_ID_REUSE_POLICY terminate_if_running cannot be combined with WORKFLOW_ID_CONFLICT_POLICY %s", cfg.WorkflowIDConflictPolicy)
+		}
+		conflict = enumspb.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED
+	}
+	return idPolicies{reuse: reuse, conflict: conflict}, nil
+}
+
+// keyed returns the policies for starts named by an Idempotency-Key. A key
+// must never start a second saga, so a repeat gets the run holding the id
+// whatever the configuration: closed sagas are not reused and running ones
+// are not terminated.
+func (p idPolicies) keyed() idPolicies {
+	k := idPolicies{reuse: enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE, conflict: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL}
+	if p.conflict == enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING {
+		k.conflict = p.conflict
+	}
+	return k
+}
+
+// workflowID picks the saga's workflow id: the body's workflow_id as given,
+// or the Idempotency-Key scoped to the operation and caller, as in
+// "create:billing:order-42", so different callers and operations never
+// share a saga through the same key. Only one of them may be given.
+func workflowID(req startRequest, key, method string, who caller) (string, error) {
+	if key == "" {
+		return req.WorkflowID, nil
+	}
+	if req.WorkflowID != "" {
+		return "", fmt.Errorf("set either workflow_id or %s, not both", idempotencyKeyHeader)
+	}
+	return fmt.Sprintf("%s:%s:%s", workflowpkg.OperationName(method), url.QueryEscape(who.ID), url.QueryEscape(key)), nil
+}
@@ -1,27 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
 	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
 	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
 	"github.com/gin-gonic/gin"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
 )
 
 type startRequest struct {
-	WorkflowID string         `json:"workflow_id"`
-	Data       map[string]any `json:"data"`
-	ID1        string         `json:"id1,omitempty"`
-	ID2        string         `json:"id2,omitempty"`
-	ID3        string         `json:"id3,omitempty"`
+	WorkflowID string                    `json:"workflow_id"`
+	Data       map[string]any            `json:"data"`
+	StepData   map[string]map[string]any `json:"step_data,omitempty"`
+	ID1        string                    `json:"id1,omitempty"`
+	ID2        string                    `json:"id2,omitempty"`
+	ID3        string                    `json:"id3,omitempty"`
+
+	// StepMethods overrides the operation's HTTP method per step, e.g. {"step1": "GET"}
+	StepMethods map[string]string `json:"step_methods,omitempty"`
+	// BusinessKey is indexed for GET /sagas?business_key=... when search attributes are enabled
+	BusinessKey string `json:"business_key,omitempty"`
+	// CallbackURL receives the saga outcome when it ends, signed with the
+	// configured secret named CallbackSecretName
+	CallbackURL        string `json:"callback_url,omitempty"`
+	CallbackSecretName string `json:"callback_secret_name,omitempty"`
 }
 
 type startResponse struct {
-	RunID      string                        `json:"run_id"`
-	WorkflowID string                        `json:"workflow_id"`
-	Result     *workflowpkg.OperationResult  `json:"result,omitempty"`
+	RunID      string                       `json:"run_id"`
+	WorkflowID string                       `json:"workflow_id"`
+	Result     *workflowpkg.OperationResult `json:"result,omitempty"`
+	// Existing is set when the workflow id was taken and the response
+	// describes the saga already started with it; Status is its saga status.
+	Existing bool   `json:"existing,omitempty"`
+	Status   string `json:"status,omitempty"`
 }
 
 func main() {
@@ -30,28 +53,120 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	r := gin.Default()
-	r.POST("/create", func(c *gin.Context) {
+	cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace})
+	if err != nil {
+		log.Fatalf("unable to create Temporal client: %v", err)
+	}
+	defer cl.Close()
+
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	srv := &http.Server{Addr: ":" + cfg.ServerPort, Handler: r}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("API listening on :%s", cfg.ServerPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("api server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("API shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Event streams and ?wait=true requests may outlive the grace period.
+		log.Printf("api server shutdown: %v", err)
+		_ = srv.Close()
+	}
+}
+
+// startSaga returns the handler starting a saga for method. Creates have no
+// resource ids yet, so id1..id3 are only passed on for updates and deletes.
+// A start whose workflow id is taken answers for the saga that holds it.
+func startSaga(cfg config.Config, cl client.Client, p idPolicies, v *requestValidator, method string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v.maxBodyBytes > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, v.maxBodyBytes)
+		}
 		var req startRequest
-		if err := c.BindJSON(&req); err != nil {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+				return
+			}
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace})
+		key := c.GetHeader(idempotencyKeyHeader)
+		id, err := workflowID(req, key, method, callerOf(c))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.WorkflowID = id
+		policies := p
+		if key != "" {
+			policies = p.keyed()
+		}
+		if errs := v.validate(req, method); len(errs) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, validationError{Error: "invalid request", Fields: errs})
 			return
 		}
-		defer cl.Close()
 
-		input := workflowpkg.OperationInput{Method: http.MethodPost, Data: req.Data}
-		we, err := cl.ExecuteWorkflow(c, client.StartWorkflowOptions{TaskQueue: cfg.TemporalTaskQueue, ID: req.WorkflowID}, workflowpkg.SagaWorkflow, cfg, input)
+		input := workflowpkg.OperationInput{Method: method, Data: req.Data, StepData: req.StepData, StepMethods: req.StepMethods, CallbackURL: req.CallbackURL, CallbackSecretName: req.CallbackSecretName}
+		// Creates have no resource ids yet, so ids are only passed on to steps
+		// that act on an existing resource.
+		if req.stepMethod("step1", method) != http.MethodPost {
+			input.ID1 = req.ID1
+		}
+		if req.stepMethod("step2", method) != http.MethodPost {
+			input.ID2 = req.ID2
+		}
+		if req.stepMethod("step3", method) != http.MethodPost {
+			input.ID3 = req.ID3
+		}
+		rec, err := newStartRecord(req, method)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		resp := startResponse{RunID: we.GetRunID(), WorkflowID: we.GetID()}
+		we, err := cl.ExecuteWorkflow(c, startOptions(cfg, policies, req, input.Method, callerOf(c), rec), workflowpkg.SagaWorkflow, cfg, input)
+		var started *serviceerror.WorkflowExecutionAlreadyStarted
+		existing := errors.As(err, &started)
+		if existing {
+			we = cl.GetWorkflow(c, req.WorkflowID, started.RunId)
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// use_existing answers a repeat like a fresh start; the run's start
+		// record tells whether this request started it.
+		if existing || policies.conflict == enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING {
+			prev, err := startedRecord(c, cl, req.WorkflowID, we.GetRunID())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			existing = prev.ID != rec.ID
+			if existing && prev.Hash != "" && prev.Hash != rec.Hash {
+				c.JSON(http.StatusConflict, gin.H{"error": "workflow id already used by a different request", "workflow_id": req.WorkflowID})
+				return
+			}
+		}
+		resp := startResponse{RunID: we.GetRunID(), WorkflowID: we.GetID(), Existing: existing}
+		if existing {
+			saga, err := describeSaga(c, cl, req.WorkflowID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			resp.Status = saga.Status
+		}
 		if c.Query("wait") == "true" {
 			var out workflowpkg.OperationResult
 			if err := we.Get(c, &out); err != nil {
@@ -61,76 +176,155 @@ func main() {
 			resp.Result = &out
 		}
 		c.JSON(http.StatusOK, resp)
-	})
+	}
+}
 
-	r.POST("/delete", func(c *gin.Context) {
-		var req startRequest
-		if err := c.BindJSON(&req); err != nil {
+// newRouter wires the API routes onto the shared Temporal client.
+func newRouter(cfg config.Config, cl client.Client) (*gin.Engine, error) {
+	p, err := parseIDPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	v, err := newRequestValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ac, err := newAccessControl(cfg.APIAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	r := gin.Default()
+	r.GET("/openapi.json", serveOpenAPI)
+	// Everything but the spec and partner callbacks, which carry their own task token.
+	api := r.Group("", ac.authenticate())
+	api.POST("/create", ac.authorize(opCreate), startSaga(cfg, cl, p, v, http.MethodPost))
+	api.POST("/update", ac.authorize(opUpdate), startSaga(cfg, cl, p, v, http.MethodPut))
+	api.POST("/delete", ac.authorize(opDelete), startSaga(cfg, cl, p, v, http.MethodDelete))
+
+	api.GET("/sagas", ac.authorize(opRead), func(c *gin.Context) {
+		var f sagaFilter
+		if err := c.ShouldBindQuery(&f); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		resp, err := listSagas(c, cl, cfg, f)
+		if errors.Is(err, errInvalidFilter) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	})
 
-		cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace})
+	api.GET("/sagas/:id", ac.authorize(opRead), func(c *gin.Context) {
+		resp, err := describeSaga(c, cl, c.Param("id"))
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saga not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		defer cl.Close()
+		c.JSON(http.StatusOK, resp)
+	})
 
-		input := workflowpkg.OperationInput{Method: http.MethodDelete, Data: req.Data, ID1: req.ID1, ID2: req.ID2, ID3: req.ID3}
-		we, err := cl.ExecuteWorkflow(c, client.StartWorkflowOptions{TaskQueue: cfg.TemporalTaskQueue, ID: req.WorkflowID}, workflowpkg.SagaWorkflow, cfg, input)
+	// Live progress as server-sent events; reconnecting clients resume after Last-Event-ID.
+	api.GET("/sagas/:id/events", ac.authorize(opRead), func(c *gin.Context) {
+		streamSagaEvents(c, cl, cfg, c.Param("id"), parseLastEventID(c.GetHeader("Last-Event-ID")))
+	})
+
+	// Cancelling compensates the completed steps; ?wait=true returns the final status.
+	api.POST("/sagas/:id/cancel", ac.authorize(opCancel), ac.ownerOnly(cfg, cl), func(c *gin.Context) {
+		id := c.Param("id")
+		// Temporal records no reason for cancellations, so the caller is logged.
+		log.Printf("saga %s: cancel requested by %s", id, requester(c, cfg))
+		err := cl.CancelWorkflow(c, id, "")
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saga not found or already closed"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		resp := startResponse{RunID: we.GetRunID(), WorkflowID: we.GetID()}
-		if c.Query("wait") == "true" {
-			var out workflowpkg.OperationResult
-			if err := we.Get(c, &out); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "workflow_id": we.GetID(), "run_id": we.GetRunID()})
-				return
-			}
-			resp.Result = &out
+		if c.Query("wait") != "true" {
+			c.JSON(http.StatusAccepted, gin.H{"workflow_id": id, "status": "cancel_requested"})
+			return
+		}
+		// The run ends cancelled once compensation is done; describe it for the outcome.
+		_ = cl.GetWorkflow(c, id, "").Get(c, nil)
+		resp, err := describeSaga(c, cl, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 		c.JSON(http.StatusOK, resp)
 	})
 
-	r.POST("/update", func(c *gin.Context) {
-		var req startRequest
-		if err := c.BindJSON(&req); err != nil {
+	// Terminating stops a saga immediately, without compensation.
+	api.POST("/sagas/:id/terminate", ac.authorize(opTerminate), adminOnly(cfg), func(c *gin.Context) {
+		var req terminateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace})
+		id := c.Param("id")
+		// The caller is recorded with the reason in the termination event.
+		log.Printf("saga %s: terminate requested by %s: %s", id, requester(c, cfg), req.Reason)
+		err := cl.TerminateWorkflow(c, id, "", req.Reason, callerOf(c))
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saga not found or already closed"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		defer cl.Close()
+		c.JSON(http.StatusOK, gin.H{"workflow_id": id, "status": "terminated", "reason": req.Reason})
+	})
 
-		input := workflowpkg.OperationInput{Method: http.MethodPut, Data: req.Data, ID1: req.ID1, ID2: req.ID2, ID3: req.ID3}
-		we, err := cl.ExecuteWorkflow(c, client.StartWorkflowOptions{TaskQueue: cfg.TemporalTaskQueue, ID: req.WorkflowID}, workflowpkg.SagaWorkflow, cfg, input)
+	// Partners complete async (callback-enabled) steps here.
+	r.POST(activities.CallbackPath+":token", func(c *gin.Context) {
+		// Sealed tokens cannot be forged, so an invalid one is reported like
+		// one whose step has gone.
+		token, err := activities.OpenTaskToken(cfg.CallbackSecret, c.Param("token"))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown callback"})
 			return
 		}
-		resp := startResponse{RunID: we.GetRunID(), WorkflowID: we.GetID()}
-		if c.Query("wait") == "true" {
-			var out workflowpkg.OperationResult
-			if err := we.Get(c, &out); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "workflow_id": we.GetID(), "run_id": we.GetRunID()})
-				return
-			}
-			resp.Result = &out
+		var req activities.CallbackRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-		c.JSON(http.StatusOK, resp)
+
+		result, stepErr := req.Outcome()
+		if stepErr != nil {
+			err = cl.CompleteActivity(c, token, nil, stepErr)
+		} else {
+			err = cl.CompleteActivity(c, token, result, nil)
+		}
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "step is no longer waiting for a callback"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "accepted"})
 	})
 
-	log.Printf("API listening on :%s", cfg.ServerPort)
-	if err := r.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatalf("api server failed: %v", err)
-	}
+	return r, nil
 }
-
-
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+)
+
+func testConfig() config.Config {
+	return config.Config{TemporalTaskQueue: "q", WorkflowIDReusePolicy: "reject_duplicate", WorkflowIDConflictPolicy: "fail", CallbackSecret: "callback-secret"}
+}
+
+func Test_StartSaga_SharedClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cl := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("saga-1")
+	run.On("GetRunID").Return("run-1")
+	var inputs []workflowpkg.OperationInput
+	cl.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool { return o.ID == "saga-1" }),
+		mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { inputs = append(inputs, args.Get(4).(workflowpkg.OperationInput)) }).
+		Return(run, nil)
+
+	r, err := newRouter(testConfig(), cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"workflow_id": "saga-1", "data": {"k": "v"}, "id1": "a", "id2": "b", "id3": "c"}`
+	for _, path := range []string{"/create", "/update", "/delete"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		var resp startResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || err != nil || resp.RunID != "run-1" {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body)
+		}
+	}
+
+	want := []struct{ method, id1 string }{{http.MethodPost, ""}, {http.MethodPut, "a"}, {http.MethodDelete, "a"}}
+	for i, in := range inputs {
+		if in.Method != want[i].method || in.ID1 != want[i].id1 || in.Data["k"] != "v" {
+			t.Fatalf("unexpected input %d: %+v", i, in)
+		}
+	}
+	cl.AssertNumberOfCalls(t, "ExecuteWorkflow", 3)
+}
+
+// withStartRecord answers DescribeWorkflowExecution of saga-1/run-1 with a
+// memo holding the start record rec returns.
+func withStartRecord(t *testing.T, cl *mocks.Client, rec func() startRecord) {
+	t.Helper()
+	cl.On("DescribeWorkflowExecution", mock.Anything, "saga-1", "run-1").Return(
+		func(context.Context, string, string) *workflowservice.DescribeWorkflowExecutionResponse {
+			p, err := converter.GetDefaultDataConverter().ToPayload(rec())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return &workflowservice.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+				Memo: &commonpb.Memo{Fields: map[string]*commonpb.Payload{requestMemoKey: p}},
+			}}
+		}, nil)
+}
+
+func Test_StartSaga_ExistingRun(t *testing.T) {
+	const body = `{"workflow_id": "saga-1", "data": {"k": "v"}}`
+	first, err := newStartRecord(startRequest{WorkflowID: "saga-1", Data: map[string]any{"k": "v"}}, http.MethodPost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name, reuse, conflict, body string
+		alreadyStarted              bool
+		want                        int
+	}{
+		{"closed saga, same request", "reject_duplicate", "fail", body, true, http.StatusOK},
+		{"running saga, use_existing", "allow_duplicate", "use_existing", body, false, http.StatusOK},
+		{"closed saga, different request", "reject_duplicate", "fail", `{"workflow_id": "saga-1", "data": {"k": "w"}}`, true, http.StatusConflict},
+		{"running saga, use_existing, different request", "allow_duplicate", "use_existing", `{"workflow_id": "saga-1"}`, false, http.StatusConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			cl := mocks.NewClient(t)
+			if tc.want == http.StatusOK {
+				cl = mockSaga(t, enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED, &workflowpkg.SagaProgress{Method: "POST", Status: workflowpkg.StatusCompleted})
+			}
+			run := mocks.NewWorkflowRun(t)
+			run.On("GetRunID").Return("run-1")
+			if tc.want == http.StatusOK {
+				// The response describes the completed run, result included
+				run.On("GetID").Return("saga-1")
+				run.On("Get", mock.Anything, mock.Anything).Return(nil)
+			}
+			if tc.alreadyStarted || tc.want == http.StatusOK {
+				cl.On("GetWorkflow", mock.Anything, "saga-1", "run-1").Return(run)
+			}
+			call := cl.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			if tc.alreadyStarted {
+				call.Return(nil, serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-1"))
+			} else {
+				call.Return(run, nil)
+			}
+			withStartRecord(t, cl, func() startRecord { return first })
+
+			cfg := testConfig()
+			cfg.WorkflowIDReusePolicy, cfg.WorkflowIDConflictPolicy = tc.reuse, tc.conflict
+			r, err := newRouter(cfg, cl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(tc.body)))
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d %s", tc.want, w.Code, w.Body)
+			}
+			if tc.want != http.StatusOK {
+				return
+			}
+			var resp startResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Existing || resp.RunID != "run-1" || resp.Status != workflowpkg.StatusCompleted {
+				t.Fatalf("unexpected response %+v", resp)
+			}
+		})
+	}
+}
+
+func Test_StartSaga_UseExistingFreshStart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cl := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("saga-1")
+	run.On("GetRunID").Return("run-1")
+	var started startRecord
+	cl.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			started = args.Get(1).(client.StartWorkflowOptions).Memo[requestMemoKey].(startRecord)
+		}).
+		Return(run, nil)
+	withStartRecord(t, cl, func() startRecord { return started })
+
+	cfg := testConfig()
+	cfg.WorkflowIDConflictPolicy = "use_existing"
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(`{"workflow_id": "saga-1"}`)))
+	var resp startResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+	if resp.Existing || resp.Status != "" {
+		t.Fatalf("fresh start reported as existing: %+v", resp)
+	}
+}
+
+func Test_StartSaga_IdempotencyKeyRepeatAfterClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "create::order-42"
+	first, err := newStartRecord(startRequest{WorkflowID: id, Data: map[string]any{"k": "v"}}, http.MethodPost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memo, err := converter.GetDefaultDataConverter().ToPayload(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := mocks.NewClient(t)
+	var opts client.StartWorkflowOptions
+	// The first saga has completed: rejecting the duplicate id is what
+	// keeps the repeat from starting a second one.
+	cl.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { opts = args.Get(1).(client.StartWorkflowOptions) }).
+		Return(nil, serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-1"))
+	cl.On("DescribeWorkflowExecution", mock.Anything, id, mock.Anything).Return(&workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: id, RunId: "run-1"},
+			Status:    enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED,
+			Memo:      &commonpb.Memo{Fields: map[string]*commonpb.Payload{requestMemoKey: memo}},
+		},
+	}, nil)
+	cl.On("QueryWorkflow", mock.Anything, id, "run-1", workflowpkg.ProgressQuery).
+		Return(jsonValue{workflowpkg.SagaProgress{Method: "POST", Status: workflowpkg.StatusCompleted}}, nil)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return(id)
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Return(nil)
+	cl.On("GetWorkflow", mock.Anything, id, "run-1").Return(run)
+
+	cfg := testConfig()
+	cfg.WorkflowIDReusePolicy, cfg.WorkflowIDConflictPolicy = "allow_duplicate", "terminate_existing"
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(`{"data": {"k": "v"}}`))
+	req.Header.Set(idempotencyKeyHeader, "order-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if opts.WorkflowIDReusePolicy != enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE || opts.WorkflowIDConflictPolicy != enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL {
+		t.Fatalf("keyed start used policies %s/%s", opts.WorkflowIDReusePolicy, opts.WorkflowIDConflictPolicy)
+	}
+	var resp startResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+	if !resp.Existing || resp.RunID != "run-1" || resp.Status != workflowpkg.StatusCompleted {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func Test_WorkflowID_ScopesIdempotencyKeys(t *testing.T) {
+	billing := caller{ID: "billing", Scheme: "api_key"}
+	ids := map[string]bool{}
+	for _, tc := range []struct {
+		method string
+		who    caller
+	}{
+		{http.MethodPost, billing},
+		{http.MethodDelete, billing},
+		{http.MethodPost, caller{ID: "ops", Scheme: "api_key"}},
+		{http.MethodPost, caller{}},
+	} {
+		id, err := workflowID(startRequest{}, "order-42", tc.method, tc.who)
+		if err != nil || ids[id] {
+			t.Fatalf("%s/%s: got %q, %v", tc.method, tc.who.ID, id, err)
+		}
+		ids[id] = true
+	}
+	if id, _ := workflowID(startRequest{}, "order-42", http.MethodPost, billing); id != "create:billing:order-42" {
+		t.Fatalf("unexpected workflow id %q", id)
+	}
+	if id, _ := workflowID(startRequest{WorkflowID: "saga-1"}, "", http.MethodPost, billing); id != "saga-1" {
+		t.Fatalf("explicit workflow_id changed to %q", id)
+	}
+}
+
+func Test_StartSaga_IdempotencyKeyWithWorkflowID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, err := newRouter(testConfig(), mocks.NewClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(`{"workflow_id": "saga-1"}`))
+	req.Header.Set(idempotencyKeyHeader, "saga-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %s", w.Code, w.Body)
+	}
+}
+
+func Test_ParseIDPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		reuse, conflict string
+		ok              bool
+	}{
+		{"reject_duplicate", "fail", true},
+		{"allow_duplicate_failed_only", "use_existing", true},
+		{"terminate_if_running", "fail", true},
+		{"terminate_if_running", "use_existing", false},
+		{"sometimes", "fail", false},
+		{"allow_duplicate", "", false},
+	} {
+		_, err := parseIDPolicies(config.Config{WorkflowIDReusePolicy: tc.reuse, WorkflowIDConflictPolicy: tc.conflict})
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s/%s: unexpected error %v", tc.reuse, tc.conflict, err)
+		}
+	}
+}
+
+func Test_Callback_SealedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig()
+	cl := mocks.NewClient(t)
+	cl.On("CompleteActivity", mock.Anything, []byte("task-token"), mock.Anything, nil).Return(nil).Once()
+	r, err := newRouter(cfg, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := activities.SealTaskToken(cfg.CallbackSecret, []byte("task-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := activities.SealTaskToken("another-secret", []byte("task-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, token string
+		want        int
+	}{
+		{"plain task token", "dGFzay10b2tlbg", http.StatusNotFound},
+		{"sealed with another secret", forged, http.StatusNotFound},
+		{"sealed", sealed, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, activities.CallbackPath+tc.token, strings.NewReader(`{"id": "b2"}`)))
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.want, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec describes every route of newRouter; Test_OpenAPI_MatchesRoutes
+// keeps the two in sync.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func serveOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
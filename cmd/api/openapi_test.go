@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/sagaclient"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/mocks"
+)
+
+type openAPIDoc struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+				Ref  string `json:"$ref"`
+			} `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return doc
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func Test_OpenAPI_MatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, err := newRouter(testConfig(), mocks.NewClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var routes, documented []string
+	for _, rt := range r.Routes() {
+		routes = append(routes, rt.Method+" "+rt.Path)
+	}
+	for path, ops := range loadOpenAPI(t).Paths {
+		for method := range ops {
+			documented = append(documented, strings.ToUpper(method)+" "+pathParam.ReplaceAllString(path, ":$1"))
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+	if !reflect.DeepEqual(routes, documented) {
+		t.Fatalf("openapi.json paths differ from the router:\nroutes:     %v\ndocumented: %v", routes, documented)
+	}
+}
+
+// jsonFields returns the JSON property names of a struct type with the JSON
+// type of each.
+func jsonFields(typ reflect.Type) map[string]string {
+	fields := map[string]string{}
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "-" && name != "" {
+			fields[name] = jsonType(typ.Field(i).Type)
+		}
+	}
+	return fields
+}
+
+// jsonType returns the JSON schema type a Go type encodes to, empty for any.
+func jsonType(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return ""
+}
+
+// The server and sagaclient types of each schema; schemas without types are
+// ad hoc gin.H bodies.
+var schemaTypes = map[string][]any{
+	"StartRequest":      {startRequest{}, sagaclient.StartRequest{}},
+	"StartResponse":     {startResponse{}, sagaclient.StartResponse{}},
+	"OperationResult":   {workflowpkg.OperationResult{}, sagaclient.OperationResult{}},
+	"StepProgress":      {workflowpkg.StepProgress{}, sagaclient.StepProgress{}},
+	"SagaStatus":        {sagaStatusResponse{}, sagaclient.SagaStatus{}},
+	"SagaSummary":       {sagaSummary{}, sagaclient.SagaSummary{}},
+	"SagaList":          {sagaListResponse{}, sagaclient.SagaList{}},
+	"StepResult":        {activities.StepResult{}, sagaclient.StepResult{}},
+	"SagaEvent":         {sagaEvent{}, sagaclient.SagaEvent{}},
+	"TerminateRequest":  {terminateRequest{}},
+	"CallbackRequest":   {activities.CallbackRequest{}},
+	"FieldError":        {fieldError{}, sagaclient.FieldError{}},
+	"ValidationError":   {validationError{}},
+	"CancelAccepted":    nil,
+	"TerminateResponse": nil,
+	"CallbackAccepted":  nil,
+	"Error":             nil,
+}
+
+func Test_OpenAPI_SchemasMatchTypes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	for schema, def := range doc.Components.Schemas {
+		types, ok := schemaTypes[schema]
+		if !ok {
+			t.Errorf("schema %s is not mapped to Go types", schema)
+		}
+		props := map[string]string{}
+		for name, p := range def.Properties {
+			props[name] = p.Type
+			if p.Ref != "" {
+				props[name] = "object"
+			}
+		}
+		for _, v := range types {
+			got := jsonFields(reflect.TypeOf(v))
+			for name, typ := range got {
+				// Fields of any type match every schema type.
+				if _, ok := props[name]; ok && typ == "" {
+					got[name] = props[name]
+				}
+			}
+			if !reflect.DeepEqual(got, props) {
+				t.Errorf("schema %s has %v, %T has %v", schema, props, v, got)
+			}
+		}
+	}
+}
+
+// clientMethods maps the operations sagaclient covers to its methods.
+var clientMethods = map[string]string{
+	"createSaga":       "Create",
+	"updateSaga":       "Update",
+	"deleteSaga":       "Delete",
+	"listSagas":        "List",
+	"getSaga":          "Get",
+	"streamSagaEvents": "Events",
+	"cancelSaga":       "Cancel",
+	"terminateSaga":    "Terminate",
+	// Partners complete steps and the spec is for tooling; neither is for
+	// Go services.
+	"completeStep": "",
+	"getOpenAPI":   "",
+}
+
+func Test_OpenAPI_OperationsHaveClientMethods(t *testing.T) {
+	client := reflect.TypeOf(&sagaclient.Client{})
+	for path, ops := range loadOpenAPI(t).Paths {
+		for method, op := range ops {
+			name, ok := clientMethods[op.OperationID]
+			if !ok {
+				t.Errorf("%s %s (%s) has no sagaclient method", method, path, op.OperationID)
+				continue
+			}
+			if _, found := client.MethodByName(name); name != "" && !found {
+				t.Errorf("sagaclient.Client has no %s method for %s", name, op.OperationID)
+			}
+		}
+	}
+}
+
+func Test_SagaClient_AgainstRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cl := mockSaga(t, enumspb.WORKFLOW_EXECUTION_STATUS_FAILED, &workflowpkg.SagaProgress{
+		Method: "POST", Status: workflowpkg.StatusCompensated, FailureCause: "external API error: 500 fail2",
+		Steps: []workflowpkg.StepProgress{{Name: "step1", Service: "api1", Status: workflowpkg.StatusCompensated, ResourceID: "a1"}},
+	})
+	it := mocks.NewHistoryEventIterator(t)
+	for _, e := range compensatedHistory(t) {
+		it.On("HasNext").Return(true).Once()
+		it.On("Next").Return(e, nil).Once()
+	}
+	cl.On("GetWorkflowHistory", mock.Anything, "saga-1", "", true, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT).Return(it)
+	r, err := newRouter(testConfig(), cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	sc := sagaclient.New(srv.URL)
+	ctx := context.Background()
+
+	status, err := sc.Get(ctx, "saga-1")
+	if err != nil || status.Status != workflowpkg.StatusCompensated || len(status.Steps) != 1 || status.Steps[0].ResourceID != "a1" {
+		t.Fatalf("unexpected status %+v: %v", status, err)
+	}
+
+	var names []string
+	err = sc.Events(ctx, "saga-1", 0, func(ev sagaclient.SagaEvent) error {
+		names = append(names, ev.Name)
+		return nil
+	})
+	want := []string{eventStepStarted, eventStepCompleted, eventStepStarted, eventStepFailed, eventCompensationStarted, eventCompensationFinished, eventSagaFailed}
+	if err != nil || !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected events %v, got %v: %v", want, names, err)
+	}
+
+	_, err = sc.Update(ctx, sagaclient.StartRequest{ID1: "a"})
+	var apiErr *sagaclient.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || len(apiErr.Fields) != 2 {
+		t.Fatalf("expected a 422 with two field errors, got %v", err)
+	}
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("openapi.json not served: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	enumspb "go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// sagaStatusResponse is returned by GET /sagas/:id.
+type sagaStatusResponse struct {
+	WorkflowID   string                       `json:"workflow_id"`
+	RunID        string                       `json:"run_id"`
+	Status       string                       `json:"status"`
+	Method       string                       `json:"method,omitempty"`
+	StartTime    *time.Time                   `json:"start_time,omitempty"`
+	CloseTime    *time.Time                   `json:"close_time,omitempty"`
+	Result       *workflowpkg.OperationResult `json:"result,omitempty"`
+	FailureCause string                       `json:"failure_cause,omitempty"`
+	Steps        []workflowpkg.StepProgress   `json:"steps,omitempty"`
+	// ProgressError is set when the progress query failed, e.g. because no worker is running.
+	ProgressError string `json:"progress_error,omitempty"`
+}
+
+// closedStatuses names the terminal states that carry no saga progress of their own.
+var closedStatuses = map[enumspb.WorkflowExecutionStatus]string{
+	enumspb.WORKFLOW_EXECUTION_STATUS_CANCELED:         "canceled",
+	enumspb.WORKFLOW_EXECUTION_STATUS_TERMINATED:       "terminated",
+	enumspb.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:        "timed_out",
+	enumspb.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW: "continued_as_new",
+}
+
+// describeSaga combines the execution description, the saga progress query
+// and, for closed sagas, the workflow result into one status.
+func describeSaga(ctx context.Context, cl client.Client, workflowID string) (sagaStatusResponse, error) {
+	resp := sagaStatusResponse{WorkflowID: workflowID}
+	desc, err := cl.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return resp, err
+	}
+	info := desc.GetWorkflowExecutionInfo()
+	resp.RunID = info.GetExecution().GetRunId()
+	if ts := info.GetStartTime(); ts != nil {
+		t := ts.AsTime()
+		resp.StartTime = &t
+	}
+	if ts := info.GetCloseTime(); ts != nil {
+		t := ts.AsTime()
+		resp.CloseTime = &t
+	}
+
+	var progress workflowpkg.SagaProgress
+	if v, err := cl.QueryWorkflow(ctx, workflowID, resp.RunID, workflowpkg.ProgressQuery); err != nil {
+		resp.ProgressError = err.Error()
+	} else if err := v.Get(&progress); err != nil {
+		resp.ProgressError = err.Error()
+	}
+	resp.Method = progress.Method
+	resp.Steps = progress.Steps
+	resp.FailureCause = progress.FailureCause
+
+	switch info.GetStatus() {
+	case enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		resp.Status = workflowpkg.StatusRunning
+		if progress.Status == workflowpkg.StatusCompensating {
+			resp.Status = progress.Status
+		}
+	case enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		resp.Status = workflowpkg.StatusCompleted
+		var out workflowpkg.OperationResult
+		if err := cl.GetWorkflow(ctx, workflowID, resp.RunID).Get(ctx, &out); err != nil {
+			return resp, err
+		}
+		resp.Result = &out
+	case enumspb.WORKFLOW_EXECUTION_STATUS_FAILED:
+		// Compensated when every completed step was rolled back, per the saga's own bookkeeping.
+		resp.Status = workflowpkg.StatusFailed
+		if progress.Status == workflowpkg.StatusCompensated {
+			resp.Status = progress.Status
+		}
+		if resp.FailureCause == "" {
+			if err := cl.GetWorkflow(ctx, workflowID, resp.RunID).Get(ctx, nil); err != nil {
+				resp.FailureCause = err.Error()
+			}
+		}
+	default:
+		resp.Status = closedStatuses[info.GetStatus()]
+	}
+	return resp, nil
+}
+
+// startOptions returns the start options of a saga, indexing it by operation
+// and business key when search attributes are enabled. Starts refused by the
+// id policies fail with WorkflowExecutionAlreadyStarted. An authenticated
+// caller is recorded in the memo for audit, next to the start record.
+func startOptions(cfg config.Config, p idPolicies, req startRequest, method string, who caller, rec startRecord) client.StartWorkflowOptions {
+	opts := client.StartWorkflowOptions{
+		TaskQueue:                                cfg.TemporalTaskQueue,
+		ID:                                       req.WorkflowID,
+		WorkflowIDReusePolicy:                    p.reuse,
+		WorkflowIDConflictPolicy:                 p.conflict,
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+		Memo:                                     map[string]any{requestMemoKey: rec},
+	}
+	if who.ID != "" {
+		opts.Memo[callerMemoKey] = who
+	}
+	if cfg.SearchAttributes {
+		updates := []temporal.SearchAttributeUpdate{workflowpkg.OperationAttribute.ValueSet(workflowpkg.OperationName(method))}
+		if req.BusinessKey != "" {
+			updates = append(updates, workflowpkg.BusinessKeyAttribute.ValueSet(req.BusinessKey))
+		}
+		opts.TypedSearchAttributes = temporal.NewSearchAttributes(updates...)
+	}
+	return opts
+}
+
+// memoValue decodes the memo field key of a saga run into v; ok is false when
+// the run has no such field.
+func memoValue(ctx context.Context, cl client.Client, workflowID, runID, key string, v any) (ok bool, err error) {
+	desc, err := cl.DescribeWorkflowExecution(ctx, workflowID, runID)
+	if err != nil {
+		return false, err
+	}
+	p, ok := desc.GetWorkflowExecutionInfo().GetMemo().GetFields()[key]
+	if !ok {
+		return false, nil
+	}
+	return true, converter.GetDefaultDataConverter().FromPayload(p, v)
+}
+
+// sagaFilter holds the query parameters of GET /sagas.
+type sagaFilter struct {
+	Operation     string    `form:"operation"`
+	Status        string    `form:"status"`
+	BusinessKey   string    `form:"business_key"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	PageSize      int       `form:"page_size"`
+	NextPageToken string    `form:"next_page_token"`
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// statusQueries maps saga statuses onto visibility query clauses. Compensated
+// and failed sagas both fail in Temporal; SagaStatus tells them apart.
+var statusQueries = map[string]string{
+	workflowpkg.StatusRunning:     "ExecutionStatus = 'Running'",
+	workflowpkg.StatusCompleted:   "ExecutionStatus = 'Completed'",
+	workflowpkg.StatusCompensated: "ExecutionStatus = 'Failed' AND SagaStatus = 'compensated'",
+	workflowpkg.StatusFailed:      "ExecutionStatus = 'Failed' AND SagaStatus = 'failed'",
+	"canceled":                    "ExecutionStatus = 'Canceled'",
+	"terminated":                  "ExecutionStatus = 'Terminated'",
+	"timed_out":                   "ExecutionStatus = 'TimedOut'",
+}
+
+// errInvalidFilter marks filter errors, reported as 400.
+var errInvalidFilter = errors.New("invalid filter")
+
+// listQuery builds the visibility query for f.
+func listQuery(f sagaFilter, searchAttributes bool) (string, error) {
+	clauses := []string{"WorkflowType = 'SagaWorkflow'"}
+	needsAttributes := f.Operation != "" || f.BusinessKey != ""
+	switch f.Operation {
+	case "", "create", "update", "delete":
+	default:
+		return "", fmt.Errorf("%w: operation must be create, update or delete", errInvalidFilter)
+	}
+	if f.Operation != "" {
+		clauses = append(clauses, fmt.Sprintf("%s = %s", workflowpkg.OperationAttribute.GetName(), quote(f.Operation)))
+	}
+	if f.BusinessKey != "" {
+		clauses = append(clauses, fmt.Sprintf("%s = %s", workflowpkg.BusinessKeyAttribute.GetName(), quote(f.BusinessKey)))
+	}
+	if f.Status != "" {
+		q, ok := statusQueries[f.Status]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown status %q", errInvalidFilter, f.Status)
+		}
+		needsAttributes = needsAttributes || strings.Contains(q, "SagaStatus")
+		clauses = append(clauses, q)
+	}
+	if needsAttributes && !searchAttributes {
+		return "", fmt.Errorf("%w: operation, business_key and compensated/failed status filters require SAGA_SEARCH_ATTRIBUTES", errInvalidFilter)
+	}
+	if !f.From.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("StartTime >= '%s'", f.From.UTC().Format(time.RFC3339)))
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("StartTime < '%s'", f.To.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(clauses, " AND "), nil
+}
+
+// quote renders s as a visibility query string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
+}
+
+// sagaSummary is one entry of GET /sagas.
+type sagaSummary struct {
+	WorkflowID  string     `json:"workflow_id"`
+	RunID       string     `json:"run_id"`
+	Status      string     `json:"status"`
+	Operation   string     `json:"operation,omitempty"`
+	BusinessKey string     `json:"business_key,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	CloseTime   *time.Time `json:"close_time,omitempty"`
+}
+
+type sagaListResponse struct {
+	Sagas         []sagaSummary `json:"sagas"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
+
+// listSagas returns one page of sagas matching f.
+func listSagas(ctx context.Context, cl client.Client, cfg config.Config, f sagaFilter) (sagaListResponse, error) {
+	resp := sagaListResponse{Sagas: []sagaSummary{}}
+	query, err := listQuery(f, cfg.SearchAttributes)
+	if err != nil {
+		return resp, err
+	}
+	if f.PageSize <= 0 {
+		f.PageSize = defaultPageSize
+	}
+	token, err := base64.RawURLEncoding.DecodeString(f.NextPageToken)
+	if err != nil {
+		return resp, fmt.Errorf("%w: malformed next_page_token", errInvalidFilter)
+	}
+	out, err := cl.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace:     cfg.TemporalNamespace,
+		PageSize:      int32(min(f.PageSize, maxPageSize)),
+		NextPageToken: token,
+		Query:         query,
+	})
+	if err != nil {
+		return resp, err
+	}
+	for _, info := range out.GetExecutions() {
+		s := sagaSummary{
+			WorkflowID:  info.GetExecution().GetWorkflowId(),
+			RunID:       info.GetExecution().GetRunId(),
+			Operation:   keywordAttribute(info, workflowpkg.OperationAttribute),
+			BusinessKey: keywordAttribute(info, workflowpkg.BusinessKeyAttribute),
+		}
+		if ts := info.GetStartTime(); ts != nil {
+			t := ts.AsTime()
+			s.StartTime = &t
+		}
+		if ts := info.GetCloseTime(); ts != nil {
+			t := ts.AsTime()
+			s.CloseTime = &t
+		}
+		switch info.GetStatus() {
+		case enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING:
+			s.Status = workflowpkg.StatusRunning
+		case enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+			s.Status = workflowpkg.StatusCompleted
+		case enumspb.WORKFLOW_EXECUTION_STATUS_FAILED:
+			s.Status = workflowpkg.StatusFailed
+			if keywordAttribute(info, workflowpkg.StatusAttribute) == workflowpkg.StatusCompensated {
+				s.Status = workflowpkg.StatusCompensated
+			}
+		default:
+			s.Status = closedStatuses[info.GetStatus()]
+		}
+		resp.Sagas = append(resp.Sagas, s)
+	}
+	resp.NextPageToken = base64.RawURLEncoding.EncodeToString(out.GetNextPageToken())
+	return resp, nil
+}
+
+// keywordAttribute reads a keyword search attribute of a listed execution.
+func keywordAttribute(info *workflowpb.WorkflowExecutionInfo, key temporal.SearchAttributeKeyKeyword) string {
+	p, ok := info.GetSearchAttributes().GetIndexedFields()[key.GetName()]
+	if !ok {
+		return ""
+	}
+	var v string
+	_ = converter.GetDefaultDataConverter().FromPayload(p, &v)
+	return v
+}
+
+// terminateRequest is the body of POST /sagas/:id/terminate; the reason is
+// recorded in the workflow history.
+type terminateRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// adminTokenHeader carries the admin token for admin-only endpoints.
+const adminTokenHeader = "X-Admin-Token"
+
+// adminOnly rejects requests without the configured admin token. Admin
+// endpoints are disabled when no token is configured.
+func adminOnly(cfg config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request carries the admin token.
+func isAdmin(c *gin.Context, cfg config.Config) bool {
+	token := c.GetHeader(adminTokenHeader)
+	return cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) == 1
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// jsonValue is a converter.EncodedValue backed by a JSON round trip.
+type jsonValue struct{ v any }
+
+func (j jsonValue) HasValue() bool { return j.v != nil }
+
+func (j jsonValue) Get(ptr any) error {
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, ptr)
+}
+
+func mockSaga(t *testing.T, status enumspb.WorkflowExecutionStatus, progress *workflowpkg.SagaProgress) *mocks.Client {
+	cl := mocks.NewClient(t)
+	cl.On("DescribeWorkflowExecution", mock.Anything, "saga-1", "").Return(&workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: "saga-1", RunId: "run-1"},
+			Status:    status,
+			StartTime: timestamppb.New(time.Unix(100, 0)),
+		},
+	}, nil)
+	if progress != nil {
+		cl.On("QueryWorkflow", mock.Anything, "saga-1", "run-1", workflowpkg.ProgressQuery).Return(jsonValue{*progress}, nil)
+	} else {
+		cl.On("QueryWorkflow", mock.Anything, "saga-1", "run-1", workflowpkg.ProgressQuery).Return(nil, errors.New("no poller"))
+	}
+	return cl
+}
+
+func Test_DescribeSaga_Completed(t *testing.T) {
+	cl := mockSaga(t, enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED, &workflowpkg.SagaProgress{
+		Method: "POST", Status: workflowpkg.StatusCompleted,
+		Steps: []workflowpkg.StepProgress{{Name: "step1", Status: workflowpkg.StatusCompleted, ResourceID: "a1"}},
+	})
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*workflowpkg.OperationResult) = workflowpkg.OperationResult{Step1ID: "a1"}
+	}).Return(nil)
+	cl.On("GetWorkflow", mock.Anything, "saga-1", "run-1").Return(run)
+
+	resp, err := describeSaga(context.Background(), cl, "saga-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "completed" || resp.Result == nil || resp.Result.Step1ID != "a1" || resp.Method != "POST" || len(resp.Steps) != 1 {
+		t.Fatalf("unexpected status: %+v", resp)
+	}
+	if resp.StartTime == nil || !resp.StartTime.Equal(time.Unix(100, 0)) {
+		t.Fatalf("unexpected start time: %v", resp.StartTime)
+	}
+}
+
+func Test_DescribeSaga_Failed(t *testing.T) {
+	for progressStatus, want := range map[string]string{
+		workflowpkg.StatusCompensated: "compensated",
+		workflowpkg.StatusFailed:      "failed",
+	} {
+		cl := mockSaga(t, enumspb.WORKFLOW_EXECUTION_STATUS_FAILED, &workflowpkg.SagaProgress{
+			Status: progressStatus, FailureCause: "external API error: 500 fail3",
+		})
+		resp, err := describeSaga(context.Background(), cl, "saga-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != want || resp.FailureCause != "external API error: 500 fail3" {
+			t.Fatalf("unexpected status: %+v", resp)
+		}
+	}
+}
+
+func Test_DescribeSaga_RunningWithoutWorker(t *testing.T) {
+	cl := mockSaga(t, enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING, nil)
+	resp, err := describeSaga(context.Background(), cl, "saga-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "running" || resp.ProgressError == "" {
+		t.Fatalf("unexpected status: %+v", resp)
+	}
+}
+
+func Test_ListQuery(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		f      sagaFilter
+		sa     bool
+		want   string
+		errors bool
+	}{
+		{name: "all", want: "WorkflowType = 'SagaWorkflow'"},
+		{name: "running since", f: sagaFilter{Status: "running", From: from}, want: "WorkflowType = 'SagaWorkflow' AND ExecutionStatus = 'Running' AND StartTime >= '2025-01-01T00:00:00Z'"},
+		{name: "indexed", f: sagaFilter{Operation: "create", BusinessKey: "o'1", Status: "compensated"}, sa: true,
+			want: "WorkflowType = 'SagaWorkflow' AND SagaOperation = 'create' AND SagaBusinessKey = 'o\\'1' AND ExecutionStatus = 'Failed' AND SagaStatus = 'compensated'"},
+		{name: "needs attributes", f: sagaFilter{BusinessKey: "o1"}, errors: true},
+		{name: "bad operation", f: sagaFilter{Operation: "patch"}, sa: true, errors: true},
+		{name: "bad status", f: sagaFilter{Status: "done"}, errors: true},
+	} {
+		got, err := listQuery(tc.f, tc.sa)
+		if tc.errors {
+			if !errors.Is(err, errInvalidFilter) {
+				t.Fatalf("%s: expected invalid filter, got %q %v", tc.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: got %q %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func Test_ListSagas_Page(t *testing.T) {
+	keyword := func(v string) *commonpb.Payload {
+		p, _ := converter.GetDefaultDataConverter().ToPayload(v)
+		return p
+	}
+	cl := mocks.NewClient(t)
+	cl.On("ListWorkflow", mock.Anything, mock.MatchedBy(func(req *workflowservice.ListWorkflowExecutionsRequest) bool {
+		return req.PageSize == maxPageSize && string(req.NextPageToken) == "p1" &&
+			req.Query == "WorkflowType = 'SagaWorkflow' AND ExecutionStatus = 'Running'"
+	})).Return(&workflowservice.ListWorkflowExecutionsResponse{
+		Executions: []*workflowpb.WorkflowExecutionInfo{{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: "saga-1", RunId: "run-1"},
+			Status:    enumspb.WORKFLOW_EXECUTION_STATUS_FAILED,
+			SearchAttributes: &commonpb.SearchAttributes{IndexedFields: map[string]*commonpb.Payload{
+				"SagaOperation":   keyword("create"),
+				"SagaBusinessKey": keyword("order-1"),
+				"SagaStatus":      keyword("compensated"),
+			}},
+		}},
+		NextPageToken: []byte("p2"),
+	}, nil)
+
+	resp, err := listSagas(context.Background(), cl, config.Config{}, sagaFilter{
+		Status: "running", PageSize: 1000, NextPageToken: base64.RawURLEncoding.EncodeToString([]byte("p1")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sagas) != 1 {
+		t.Fatalf("unexpected sagas: %+v", resp)
+	}
+	if s := resp.Sagas[0]; s.Status != "compensated" || s.Operation != "create" || s.BusinessKey != "order-1" {
+		t.Fatalf("unexpected saga: %+v", s)
+	}
+	if token, _ := base64.RawURLEncoding.DecodeString(resp.NextPageToken); string(token) != "p2" {
+		t.Fatalf("unexpected next page token %q", resp.NextPageToken)
+	}
+}
+
+func Test_AdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		configured, sent string
+		want             int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "anything", http.StatusForbidden},
+		{"s3cret", "wrong", http.StatusForbidden},
+		{"s3cret", "s3cret", http.StatusOK},
+	} {
+		r := gin.New()
+		r.POST("/admin", adminOnly(config.Config{AdminToken: tc.configured}), func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodPost, "/admin", nil)
+		req.Header.Set(adminTokenHeader, tc.sent)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("token %q/%q: got %d, want %d", tc.configured, tc.sent, w.Code, tc.want)
+		}
+	}
+}
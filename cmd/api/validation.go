@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// maxKeyLength bounds workflow_id and business_key, which end up as
+// workflow ids and Keyword search attributes.
+const maxKeyLength = 255
+
+// fieldError is one problem with a start request. Field is a JSON path
+// such as "id1" or "data/amount".
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationError is the 422 body listing everything wrong with a request.
+type validationError struct {
+	Error  string       `json:"error"`
+	Fields []fieldError `json:"fields"`
+}
+
+// stepNames are the valid keys of step_data and step_methods.
+var stepNames = map[string]bool{"step1": true, "step2": true, "step3": true}
+
+// stepMethods are the methods a step may override the operation's with.
+var stepMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// stepMethod is the method step runs with when the saga runs method.
+func (r startRequest) stepMethod(step, method string) string {
+	if m := r.StepMethods[step]; m != "" {
+		return m
+	}
+	return method
+}
+
+// stepID is the resource id given for step, e.g. id1 for step1.
+func (r startRequest) stepID(step string) string {
+	return map[string]string{"step1": r.ID1, "step2": r.ID2, "step3": r.ID3}[step]
+}
+
+// requestValidator checks start requests before a saga is started.
+type requestValidator struct {
+	maxBodyBytes int64
+	schemas      map[string]*jsonschema.Schema // by method; nil skips the data check
+	webhookHosts []string
+	secretNames  map[string]string
+}
+
+func newRequestValidator(cfg config.Config) (*requestValidator, error) {
+	v := &requestValidator{
+		maxBodyBytes: cfg.Requests.MaxBodyBytes,
+		schemas:      map[string]*jsonschema.Schema{},
+		webhookHosts: cfg.WebhookAllowedHosts,
+		secretNames:  cfg.WebhookSecrets,
+	}
+	for method, file := range map[string]string{
+		http.MethodPost:   cfg.Requests.CreateSchemaFile,
+		http.MethodPut:    cfg.Requests.UpdateSchemaFile,
+		http.MethodDelete: cfg.Requests.DeleteSchemaFile,
+	} {
+		if file == "" {
+			continue
+		}
+		s, err := jsonschema.Compile(file)
+		if err != nil {
+			return nil, fmt.Errorf("compile request schema %s: %w", file, err)
+		}
+		v.schemas[method] = s
+	}
+	return v, nil
+}
+
+// validate returns the problems with req as a start of method, if any.
+func (v *requestValidator) validate(req startRequest, method string) []fieldError {
+	var errs []fieldError
+	add := func(field, format string, args ...any) {
+		errs = append(errs, fieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for step := range req.StepMethods {
+		if !stepNames[step] {
+			add("step_methods/"+step, "unknown step, expected step1, step2 or step3")
+		} else if !stepMethods[req.StepMethods[step]] {
+			add("step_methods/"+step, "must be GET, POST, PUT, PATCH or DELETE")
+		}
+	}
+	// Every step but a create acts on an existing resource and needs its id.
+	for _, step := range []string{"step1", "step2", "step3"} {
+		m := req.stepMethod(step, method)
+		if m == http.MethodPost || !stepMethods[m] || req.stepID(step) != "" {
+			continue
+		}
+		field := "id" + step[len("step"):]
+		if _, set := req.StepMethods[step]; set {
+			add(field, "required when %s uses %s", step, m)
+		} else {
+			add(field, "required for %s", workflowpkg.OperationName(method))
+		}
+	}
+	if len(req.WorkflowID) > maxKeyLength {
+		add("workflow_id", "must be at most %d characters", maxKeyLength)
+	}
+	if len(req.BusinessKey) > maxKeyLength {
+		add("business_key", "must be at most %d characters", maxKeyLength)
+	}
+	for step := range req.StepData {
+		if !stepNames[step] {
+			add("step_data/"+step, "unknown step, expected step1, step2 or step3")
+		}
+	}
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("callback_url", "must be an absolute http(s) URL")
+		} else if !activities.WebhookHostAllowed(v.webhookHosts, req.CallbackURL) {
+			add("callback_url", "host %q is not allowed", u.Hostname())
+		}
+	}
+	if _, ok := v.secretNames[req.CallbackSecretName]; req.CallbackSecretName != "" && !ok {
+		add("callback_secret_name", "unknown secret")
+	}
+	if s := v.schemas[method]; s != nil {
+		var data any = map[string]any{}
+		if req.Data != nil {
+			data = map[string]any(req.Data)
+		}
+		var ve *jsonschema.ValidationError
+		if err := s.Validate(data); errors.As(err, &ve) {
+			errs = append(errs, schemaErrors(ve)...)
+		} else if err != nil {
+			add("data", "%v", err)
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// schemaErrors flattens a schema validation error into its leaf causes,
+// which name the offending value.
+func schemaErrors(ve *jsonschema.ValidationError) []fieldError {
+	if len(ve.Causes) == 0 {
+		return []fieldError{{Field: "data" + ve.InstanceLocation, Message: ve.Message}}
+	}
+	var errs []fieldError
+	for _, c := range ve.Causes {
+		errs = append(errs, schemaErrors(c)...)
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/gin-gonic/gin"
+	"go.temporal.io/sdk/mocks"
+)
+
+func postStart(t *testing.T, cfg config.Config, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	// No expectations: any workflow start fails the test.
+	r, err := newRouter(cfg, mocks.NewClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return w
+}
+
+func fieldsOf(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d %s", w.Code, w.Body)
+	}
+	var resp validationError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, f := range resp.Fields {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func Test_StartValidation_RequiredIDs(t *testing.T) {
+	w := postStart(t, testConfig(), "/delete", `{"id2": "b", "step_data": {"step4": {}}, "callback_url": "/relative"}`)
+	want := []string{"callback_url", "id1", "id3", "step_data/step4"}
+	if got := fieldsOf(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func Test_StartValidation_Callbacks(t *testing.T) {
+	cfg := testConfig()
+	cfg.WebhookAllowedHosts = []string{"hooks.example.com"}
+	cfg.WebhookSecrets = map[string]string{"billing": "s3cret"}
+	v, err := newRequestValidator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := v.validate(startRequest{CallbackURL: "https://hooks.example.com/saga", CallbackSecretName: "billing"}, http.MethodPost); len(errs) > 0 {
+		t.Fatalf("allowed callback rejected: %+v", errs)
+	}
+	for body, want := range map[string][]string{
+		`{"callback_url": "http://169.254.169.254/latest"}`:                                 {"callback_url"},
+		`{"callback_url": "https://hooks.example.com/saga", "callback_secret_name": "ops"}`: {"callback_secret_name"},
+	} {
+		if got := fieldsOf(t, postStart(t, cfg, "/create", body)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %v, got %v", body, want, got)
+		}
+	}
+}
+
+func Test_StartValidation_StepMethods(t *testing.T) {
+	w := postStart(t, testConfig(), "/create", `{"step_methods": {"step1": "GET", "step2": "HEAD", "step9": "GET"}}`)
+	want := []string{"id1", "step_methods/step2", "step_methods/step9"}
+	if got := fieldsOf(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func Test_StartValidation_DataSchema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "update.json")
+	if err := os.WriteFile(schema, []byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {"amount": {"type": "number", "minimum": 0}}
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Requests.UpdateSchemaFile = schema
+
+	w := postStart(t, cfg, "/update", `{"id1": "a", "id2": "b", "id3": "c", "data": {"amount": -1}}`)
+	want := []string{"data", "data/amount"}
+	if got := fieldsOf(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func Test_StartValidation_BodyLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Requests.MaxBodyBytes = 64
+	w := postStart(t, cfg, "/create", `{"data": {"blob": "`+strings.Repeat("x", 100)+`"}}`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/caarlos0/env/v10"
+)
+
+type mockConfig struct {
+	Addr string `env:"MOCKAPI_ADDR" envDefault:":8081"`
+	// ScenarioFile holds initial failure rules in the MOCK_SCENARIO_FILE format.
+	ScenarioFile string `env:"MOCKAPI_SCENARIO_FILE"`
+	// Failures holds initial failure rules as inline JSON in the same format,
+	// e.g. {"api2":{"step2":{"fail_times":-1}}}; it takes precedence.
+	Failures string `env:"MOCKAPI_FAILURES"`
+}
+
+func main() {
+	var cfg mockConfig
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	rules, err := activities.LoadScenario(cfg.ScenarioFile)
+	if err != nil {
+		log.Fatalf("invalid MOCKAPI_SCENARIO_FILE: %v", err)
+	}
+	if cfg.Failures != "" {
+		if err := json.Unmarshal([]byte(cfg.Failures), &rules); err != nil {
+			log.Fatalf("invalid MOCKAPI_FAILURES: %v", err)
+		}
+	}
+
+	log.Printf("Mock API listening on %s", cfg.Addr)
+	if err := http.ListenAndServe(cfg.Addr, newServer(rules).routes()); err != nil {
+		log.Fatalf("mock api server failed: %v", err)
+	}
+}
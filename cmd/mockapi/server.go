@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+)
+
+// resource is one stored document.
+type resource struct {
+	ID        string         `json:"_id"`
+	Data      map[string]any `json:"data"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// inventory lists the resources of one API prefix for inspection.
+type inventory struct {
+	Live    []resource `json:"live"`
+	Deleted []resource `json:"deleted"`
+}
+
+// server is an in-memory implementation of the downstream contract
+// ExternalClient expects: POST /{api}/create and GET/PUT/PATCH/DELETE
+// /{api}/{id}, with resources kept separately per API prefix.
+//
+// Failures are injected from an activities.Scenario, the format MOCK_MODE
+// uses, keyed by API prefix and the step operation sent in the body
+// ("step1", ...), activities.CompensateOperation for DELETE, or "*".
+// FailTimes counts matching requests here rather than activity attempts.
+type server struct {
+	mu        sync.Mutex
+	apis      map[string]map[string]*resource
+	failures  activities.Scenario
+	failCount map[string]int // matching requests seen per api/operation rule
+	nextID    int
+}
+
+func newServer(rules activities.Scenario) *server {
+	return &server{apis: map[string]map[string]*resource{}, failures: rules, failCount: map[string]int{}}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_resources", s.handleResources)
+	mux.HandleFunc("/_failures", s.handleFailures)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.HandleFunc("/", s.handleAPI)
+	return mux
+}
+
+func (s *server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	api, id, ok := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	if !ok || api == "" || id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	op, allowed := operation(r.Method, id)
+	if !allowed {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body struct {
+		Operation string         `json:"operation"`
+		Data      map[string]any `json:"data"`
+	}
+	if op == "create" || op == "update" {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+			return
+		}
+	}
+	step := body.Operation
+	if op == "delete" {
+		step = activities.CompensateOperation
+	}
+	if f, fail := s.injectFailure(api, step); fail {
+		if f.Status == 0 {
+			f.Status = http.StatusInternalServerError
+		}
+		if f.Message == "" {
+			f.Message = fmt.Sprintf("injected %s failure for %s", op, api)
+		}
+		writeError(w, f.Status, f.Message)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	store := s.apis[api]
+	if store == nil {
+		store = map[string]*resource{}
+		s.apis[api] = store
+	}
+	now := time.Now().UTC()
+	if op == "create" {
+		s.nextID++
+		res := &resource{ID: fmt.Sprintf("%s-%d", api, s.nextID), Data: body.Data, CreatedAt: now, UpdatedAt: now}
+		store[res.ID] = res
+		writeJSON(w, http.StatusCreated, res.view())
+		return
+	}
+	res, found := store[id]
+	if !found || res.DeletedAt != nil {
+		writeError(w, http.StatusNotFound, "resource not found")
+		return
+	}
+	switch op {
+	case "get":
+		writeJSON(w, http.StatusOK, res.view())
+	case "update":
+		if r.Method == http.MethodPut {
+			res.Data = body.Data
+		} else {
+			if res.Data == nil {
+				res.Data = map[string]any{}
+			}
+			for k, v := range body.Data {
+				res.Data[k] = v
+			}
+		}
+		res.UpdatedAt = now
+		writeJSON(w, http.StatusOK, res.view())
+	case "delete":
+		res.DeletedAt = &now
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// operation maps a request onto a store operation.
+func operation(method, id string) (string, bool) {
+	if id == "create" {
+		return "create", method == http.MethodPost
+	}
+	switch method {
+	case http.MethodGet:
+		return "get", true
+	case http.MethodPut, http.MethodPatch:
+		return "update", true
+	case http.MethodDelete:
+		return "delete", true
+	}
+	return "", false
+}
+
+// injectFailure reports whether a request for the scenario operation step
+// should fail, applying the rule's latency either way. GET requests carry no
+// operation and only match "*".
+func (s *server) injectFailure(api, step string) (activities.Behavior, bool) {
+	s.mu.Lock()
+	key := api + "/" + step
+	f, ok := s.failures[api][step]
+	if !ok {
+		key = api + "/*"
+		f, ok = s.failures[api]["*"]
+	}
+	if !ok {
+		s.mu.Unlock()
+		return activities.Behavior{}, false
+	}
+	s.failCount[key]++
+	n := s.failCount[key]
+	s.mu.Unlock()
+
+	if f.LatencyMs > 0 {
+		time.Sleep(time.Duration(f.LatencyMs) * time.Millisecond)
+	}
+	return f, f.FailTimes < 0 || n <= f.FailTimes
+}
+
+// view is the response body: the stored data plus the id keys clients read.
+func (r *resource) view() map[string]any {
+	out := map[string]any{}
+	for k, v := range r.Data {
+		out[k] = v
+	}
+	out["_id"] = r.ID
+	out["status"] = "ok"
+	return out
+}
+
+// handleResources lists live and deleted resources per API prefix (GET) or
+// clears everything (DELETE). ?api= limits the listing to one prefix.
+func (s *server) handleResources(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		out := map[string]inventory{}
+		for api, store := range s.apis {
+			if want := r.URL.Query().Get("api"); want != "" && want != api {
+				continue
+			}
+			inv := inventory{Live: []resource{}, Deleted: []resource{}}
+			for _, res := range store {
+				if res.DeletedAt != nil {
+					inv.Deleted = append(inv.Deleted, *res)
+				} else {
+					inv.Live = append(inv.Live, *res)
+				}
+			}
+			sort.Slice(inv.Live, func(i, j int) bool { return inv.Live[i].CreatedAt.Before(inv.Live[j].CreatedAt) })
+			sort.Slice(inv.Deleted, func(i, j int) bool { return inv.Deleted[i].CreatedAt.Before(inv.Deleted[j].CreatedAt) })
+			out[api] = inv
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodDelete:
+		s.apis = map[string]map[string]*resource{}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleFailures returns (GET) or replaces (PUT) the failure rules; replacing
+// them resets the per-rule request counts.
+func (s *server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, http.StatusOK, s.failures)
+	case http.MethodPut:
+		var rules activities.Scenario
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.failures = rules
+		s.failCount = map[string]int{}
+		writeJSON(w, http.StatusOK, rules)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
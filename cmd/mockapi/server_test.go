@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+)
+
+func inspect(t *testing.T, base string) map[string]inventory {
+	t.Helper()
+	resp, err := http.Get(base + "/_resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out := map[string]inventory{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func Test_MockAPI_ServesExternalClientContract(t *testing.T) {
+	srv := httptest.NewServer(newServer(nil).routes())
+	defer srv.Close()
+	tr, err := activities.NewTransport(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	base := srv.URL + "/api1"
+
+	created, err := tr.Execute(ctx, activities.StepInput{BaseURL: base, Method: http.MethodPost, Payload: activities.RequestPayload{Operation: "step1", Data: map[string]any{"k": "v"}}})
+	if err != nil || created.ResourceID != "api1-1" {
+		t.Fatalf("create: %+v %v", created, err)
+	}
+	updated, err := tr.Execute(ctx, activities.StepInput{BaseURL: base, Method: http.MethodPatch, ResourceID: created.ResourceID, Payload: activities.RequestPayload{Data: map[string]any{"n": 2}}})
+	if err != nil || updated.Body["k"] != "v" || updated.Body["n"] != float64(2) {
+		t.Fatalf("patch: %+v %v", updated, err)
+	}
+	if _, err := tr.Execute(ctx, activities.StepInput{BaseURL: base, Method: http.MethodPost, Payload: activities.RequestPayload{Operation: "step1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Compensate(ctx, base, created.ResourceID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := tr.Execute(ctx, activities.StepInput{BaseURL: base, Method: http.MethodGet, ResourceID: created.ResourceID}); err == nil {
+		t.Fatal("expected deleted resource to be gone")
+	}
+
+	inv := inspect(t, srv.URL)["api1"]
+	if len(inv.Live) != 1 || inv.Live[0].ID != "api1-2" || len(inv.Deleted) != 1 || inv.Deleted[0].ID != "api1-1" {
+		t.Fatalf("unexpected inventory: %+v", inv)
+	}
+}
+
+func Test_MockAPI_InjectedFailures(t *testing.T) {
+	srv := httptest.NewServer(newServer(activities.Scenario{"api2": {"step2": {FailTimes: 1, Status: http.StatusServiceUnavailable}}}).routes())
+	defer srv.Close()
+	create := func() int {
+		resp, err := http.Post(srv.URL+"/api2/create", "application/json", strings.NewReader(`{"operation":"step2","data":{}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if s := create(); s != http.StatusServiceUnavailable {
+		t.Fatalf("expected first create to fail, got %d", s)
+	}
+	if s := create(); s != http.StatusCreated {
+		t.Fatalf("expected second create to succeed, got %d", s)
+	}
+
+	// Replacing the rules at runtime resets the counts
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/_failures", strings.NewReader(`{"api2":{"*":{"fail_times":-1,"status":500}}}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for i := 0; i < 3; i++ {
+		if s := create(); s != http.StatusInternalServerError {
+			t.Fatalf("expected create %d to fail, got %d", i, s)
+		}
+	}
+	if inv := inspect(t, srv.URL)["api2"]; len(inv.Live) != 1 {
+		t.Fatalf("unexpected inventory: %+v", inv)
+	}
+}
+
+func Test_MockAPI_LoadsScenarioFiles(t *testing.T) {
+	rules, err := activities.LoadScenario("../../scenarios/compensation-fails.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(rules).routes())
+	defer srv.Close()
+	tr, err := activities.NewTransport(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := tr.Execute(ctx, activities.StepInput{BaseURL: srv.URL + "/api3", Method: http.MethodPost, Payload: activities.RequestPayload{Operation: "step3"}}); err == nil || !strings.Contains(err.Error(), "409 ") {
+		t.Fatalf("expected step3 to fail with 409, got %v", err)
+	}
+	created, err := tr.Execute(ctx, activities.StepInput{BaseURL: srv.URL + "/api2", Method: http.MethodPost, Payload: activities.RequestPayload{Operation: "step2"}})
+	if err != nil {
+		t.Fatalf("step2: %v", err)
+	}
+	if err := tr.Compensate(ctx, srv.URL+"/api2", created.ResourceID); err == nil || !strings.Contains(err.Error(), "delete failed") {
+		t.Fatalf("expected compensation to fail, got %v", err)
+	}
+}
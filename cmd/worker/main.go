@@ -2,8 +2,11 @@ package main
 
 import (
 	"log"
+	"net"
+	"net/http"
 
 	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/metrics"
 	workflowpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/internal/workflow"
 	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
 	"go.temporal.io/sdk/client"
@@ -16,25 +19,49 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace})
+	mh := metrics.NewHandler()
+	defer mh.Close()
+	if cfg.MetricsAddr != "" {
+		// Bind before starting the worker so a taken or invalid address fails startup.
+		ln, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			log.Fatalf("failed to start metrics server: %v", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", mh)
+		go func() {
+			if err := http.Serve(ln, mux); err != nil {
+				log.Fatalf("metrics server failed: %v", err)
+			}
+		}()
+	}
+
+	cl, err := client.NewClient(client.Options{HostPort: cfg.TemporalAddress, Namespace: cfg.TemporalNamespace, MetricsHandler: mh})
 	if err != nil {
 		log.Fatalf("unable to create Temporal client: %v", err)
 	}
 	defer cl.Close()
 
-	w := worker.New(cl, cfg.TemporalTaskQueue, worker.Options{})
+	w := worker.New(cl, cfg.TemporalTaskQueue, worker.Options{
+		TaskQueueActivitiesPerSecond:       cfg.WorkerActivitiesPerSecond,
+		MaxConcurrentActivityExecutionSize: cfg.WorkerMaxConcurrentActivities,
+	})
 	w.RegisterWorkflow(workflowpkg.SagaWorkflow)
+	w.RegisterWorkflow(workflowpkg.CompletionWebhookWorkflow)
 
-	acts := &activities.Activities{Cfg: cfg}
+	acts, err := activities.NewActivities(cfg)
+	if err != nil {
+		log.Fatalf("failed to create activities: %v", err)
+	}
 	w.RegisterActivity(acts.Step1)
 	w.RegisterActivity(acts.Step2)
 	w.RegisterActivity(acts.Step3)
 	w.RegisterActivity(acts.Rollback)
+	w.RegisterActivity(acts.RollbackService)
+	w.RegisterActivity(acts.NotifyCompletion)
 
 	log.Printf("Worker started. TaskQueue=%s", cfg.TemporalTaskQueue)
 	if err := w.Run(worker.InterruptCh()); err != nil {
 		log.Fatalf("worker failed: %v", err)
 	}
 }
-
-
@@ -0,0 +1,226 @@
+package activities
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"golang.org/x/sync/singleflight"
+)
+
+// authenticator decorates an outbound request with credentials. body is the
+// exact request body, needed by signing strategies.
+type authenticator interface {
+	authenticate(req *http.Request, body []byte) error
+}
+
+// newAuthenticator builds the strategy selected by cfg.Type. hc is used for
+// token requests by strategies that need them.
+func newAuthenticator(cfg config.AuthConfig, hc *http.Client) (authenticator, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "", "none":
+		return noAuth{}, nil
+	case "bearer":
+		return bearerAuth{token: cfg.Token}, nil
+	case "basic":
+		return basicAuth{username: cfg.Username, password: cfg.Password}, nil
+	case "apikey":
+		return apiKeyAuth{header: cfg.APIKeyHeader, key: cfg.APIKey}, nil
+	case "oauth2":
+		if cfg.TokenURL == "" {
+			return nil, fmt.Errorf("oauth2 auth requires a token url")
+		}
+		return &oauth2Auth{cfg: cfg, httpClient: hc}, nil
+	case "hmac":
+		return hmacAuth{header: cfg.HMACHeader, secret: []byte(cfg.HMACSecret)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %s", cfg.Type)
+	}
+}
+
+type noAuth struct{}
+
+func (noAuth) authenticate(*http.Request, []byte) error { return nil }
+
+type bearerAuth struct {
+	token string
+}
+
+func (a bearerAuth) authenticate(req *http.Request, _ []byte) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) authenticate(req *http.Request, _ []byte) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+type apiKeyAuth struct {
+	header, key string
+}
+
+func (a apiKeyAuth) authenticate(req *http.Request, _ []byte) error {
+	req.Header.Set(a.header, a.key)
+	return nil
+}
+
+// hmacAuth signs "<method>\n<path>\n<timestamp>\n<body>" with HMAC-SHA256 and
+// sends the hex digest in header, with the unix timestamp in header+"-Timestamp".
+type hmacAuth struct {
+	header string
+	secret []byte
+}
+
+func (a hmacAuth) authenticate(req *http.Request, body []byte) error {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, a.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", req.Method, req.URL.RequestURI(), ts)
+	mac.Write(body)
+	req.Header.Set(a.header, hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set(a.header+"-Timestamp", ts)
+	return nil
+}
+
+// oauth2Auth implements the client-credentials grant. Tokens are cached
+// process-wide per token endpoint and client, and refreshed shortly before
+// they expire or after the downstream rejects them.
+type oauth2Auth struct {
+	cfg        config.AuthConfig
+	httpClient *http.Client
+}
+
+type cachedToken struct {
+	value   string
+	expires time.Time
+}
+
+// tokenStore caches tokens by key. Concurrent misses for one key share a
+// single token request; other keys are not held up by it.
+type tokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+	fetch  singleflight.Group
+}
+
+var tokens = &tokenStore{tokens: map[string]cachedToken{}}
+
+func (s *tokenStore) get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[key]
+	return t.value, ok && time.Now().Before(t.expires)
+}
+
+func (s *tokenStore) put(key string, t cachedToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = t
+}
+
+func (s *tokenStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+}
+
+// tokenExpirySkew refreshes tokens this long before the server says they expire.
+const tokenExpirySkew = 30 * time.Second
+
+func (a *oauth2Auth) cacheKey() string {
+	return a.cfg.TokenURL + "|" + a.cfg.ClientID + "|" + strings.Join(a.cfg.Scopes, " ")
+}
+
+func (a *oauth2Auth) authenticate(req *http.Request, _ []byte) error {
+	token, err := a.token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// invalidate drops the cached token so the next request fetches a new one.
+func (a *oauth2Auth) invalidate() {
+	tokens.delete(a.cacheKey())
+}
+
+func (a *oauth2Auth) token(ctx context.Context) (string, error) {
+	key := a.cacheKey()
+	if v, ok := tokens.get(key); ok {
+		return v, nil
+	}
+	// The request is shared by every caller waiting on key, so it must not be
+	// cut short when the first of them gives up; the client timeout bounds it.
+	ch := tokens.fetch.DoChan(key, func() (any, error) {
+		t, err := a.requestToken(context.WithoutCancel(ctx))
+		if err != nil {
+			return "", err
+		}
+		tokens.put(key, t)
+		return t.value, nil
+	})
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return "", r.Err
+		}
+		return r.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (a *oauth2Auth) requestToken(ctx context.Context) (cachedToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.cfg.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.cfg.ClientID), url.QueryEscape(a.cfg.ClientSecret))
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return cachedToken{}, fmt.Errorf("oauth2 token request failed: %d %s", resp.StatusCode, string(b))
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return cachedToken{}, fmt.Errorf("decode oauth2 token: %w", err)
+	}
+	if out.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("oauth2 token response has no access_token")
+	}
+	t := cachedToken{value: out.AccessToken}
+	if out.ExpiresIn > 0 {
+		t.expires = time.Now().Add(time.Duration(out.ExpiresIn)*time.Second - tokenExpirySkew)
+	} else {
+		t.expires = time.Now().Add(time.Hour)
+	}
+	return t, nil
+}
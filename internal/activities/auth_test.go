@@ -0,0 +1,170 @@
+package activities
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+)
+
+func Test_Auth_Strategies(t *testing.T) {
+	cases := []struct {
+		name  string
+		auth  config.AuthConfig
+		check func(r *http.Request) bool
+	}{
+		{"bearer", config.AuthConfig{Type: "bearer", Token: "t0k"}, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer t0k"
+		}},
+		{"basic", config.AuthConfig{Type: "basic", Username: "u", Password: "p"}, func(r *http.Request) bool {
+			u, p, ok := r.BasicAuth()
+			return ok && u == "u" && p == "p"
+		}},
+		{"apikey", config.AuthConfig{Type: "apikey", APIKeyHeader: "X-Key", APIKey: "k"}, func(r *http.Request) bool {
+			return r.Header.Get("X-Key") == "k"
+		}},
+		{"hmac", config.AuthConfig{Type: "hmac", HMACHeader: "X-Signature", HMACSecret: "s"}, func(r *http.Request) bool {
+			mac := hmac.New(sha256.New, []byte("s"))
+			mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n" + r.Header.Get("X-Signature-Timestamp") + "\n"))
+			return r.Header.Get("X-Signature") == hex.EncodeToString(mac.Sum(nil))
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tc.check(r) {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			}))
+			defer srv.Close()
+
+			c, err := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{Auth: tc.auth}}, "api1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.rollback(context.Background(), srv.URL, "r1"); err != nil {
+				t.Fatalf("request rejected: %v", err)
+			}
+		})
+	}
+}
+
+func Test_Auth_OAuth2CachesToken(t *testing.T) {
+	var issued int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "cid" || secret != "cs" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "expires_in": 3600})
+	}))
+	defer tokenSrv.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API2: config.ServiceConfig{Auth: config.AuthConfig{
+		Type: "oauth2", TokenURL: tokenSrv.URL, ClientID: "cid", ClientSecret: "cs",
+	}}}
+	for i := 0; i < 3; i++ {
+		c, err := NewExternalClient(cfg, "api2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.rollback(context.Background(), api.URL, "r1"); err != nil {
+			t.Fatalf("request rejected: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&issued); n != 1 {
+		t.Fatalf("expected one token request, got %d", n)
+	}
+}
+
+func Test_Auth_OAuth2ConcurrentMissesShareOneRequest(t *testing.T) {
+	var issued int32
+	release := make(chan struct{})
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issued, 1)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "expires_in": 3600})
+	}))
+	defer tokenSrv.Close()
+
+	a := &oauth2Auth{cfg: config.AuthConfig{TokenURL: tokenSrv.URL, ClientID: "concurrent"}, httpClient: tokenSrv.Client()}
+	defer a.invalidate()
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := a.token(context.Background())
+			if err == nil && tok != "at" {
+				err = fmt.Errorf("got token %q", tok)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&issued); n != 1 {
+		t.Fatalf("expected one token request, got %d", n)
+	}
+}
+
+func Test_Auth_HMACSignedAfterRateLimitWait(t *testing.T) {
+	var stale int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts, _ := strconv.ParseInt(r.Header.Get("X-Signature-Timestamp"), 10, 64)
+		if time.Now().Unix()-ts > 1 {
+			atomic.AddInt32(&stale, 1)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 5, API1: config.ServiceConfig{
+		Auth:      config.AuthConfig{Type: "hmac", HMACHeader: "X-Signature", HMACSecret: "s"},
+		RateLimit: config.RateLimitConfig{RPS: 0.4, Burst: 1},
+	}}
+	c, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.rollback(context.Background(), srv.URL, "r1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&stale); n != 0 {
+		t.Fatalf("%d requests carried a timestamp from before the rate limit wait", n)
+	}
+}
+
+func Test_Auth_UnknownType(t *testing.T) {
+	cfg := config.Config{API3: config.ServiceConfig{Auth: config.AuthConfig{Type: "kerberos"}}}
+	if _, err := NewExternalClient(cfg, "api3"); err == nil {
+		t.Fatal("expected error for unsupported auth type")
+	}
+}
@@ -0,0 +1,69 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+)
+
+// runSaga drives the three create steps of one saga through acts.
+func runSaga(b *testing.B, acts *Activities, cfg config.Config) {
+	steps := []func(context.Context, StepInput) (StepResult, error){acts.Step1, acts.Step2, acts.Step3}
+	urls := []string{cfg.API1BaseURL, cfg.API2BaseURL, cfg.API3BaseURL}
+	for i, step := range steps {
+		in := StepInput{BaseURL: urls[i], Method: http.MethodPost, Payload: RequestPayload{Operation: "bench", Data: map[string]any{"k": "v"}}}
+		if _, err := step(context.Background(), in); err != nil {
+			b.Error(err)
+			return
+		}
+	}
+}
+
+// BenchmarkConcurrentSagas compares pooled per-service clients with building
+// a fresh client (and connection) for every activity execution.
+func BenchmarkConcurrentSagas(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ResponsePayload{Status: "ok", ID: "id"})
+	}))
+	defer srv.Close()
+	cfg := config.Config{
+		API1BaseURL:        srv.URL + "/api1",
+		API2BaseURL:        srv.URL + "/api2",
+		API3BaseURL:        srv.URL + "/api3",
+		HTTPTimeoutSeconds: 5,
+	}
+	for _, svc := range []*config.ServiceConfig{&cfg.API1, &cfg.API2, &cfg.API3} {
+		svc.HTTP = config.TransportConfig{MaxIdleConns: 256, MaxIdleConnsPerHost: 256, IdleConnTimeoutSeconds: 90, KeepAliveSeconds: 30}
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		acts, err := NewActivities(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetParallelism(8)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				runSaga(b, acts, cfg)
+			}
+		})
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "sagas/s")
+	})
+
+	b.Run("per-call", func(b *testing.B) {
+		b.SetParallelism(8)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				// A zero Activities per saga mimics a new client per execution.
+				runSaga(b, &Activities{Cfg: cfg}, cfg)
+			}
+		})
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "sagas/s")
+	})
+}
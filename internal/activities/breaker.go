@@ -0,0 +1,152 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// CircuitOpenErrorType is the application error type returned while a
+// service's breaker is open. It is non-retryable so the saga compensates
+// immediately instead of burning its retry budget.
+const CircuitOpenErrorType = "CircuitOpen"
+
+// compensationKey marks the context of a compensation.
+type compensationKey struct{}
+
+// compensating marks ctx as undoing a step. Compensations bypass the
+// breaker: an open circuit would otherwise fail them non-retryably and
+// orphan the resource, while retrying them is exactly what the breaker's
+// fail-fast guards new work against.
+func compensating(ctx context.Context) context.Context {
+	return context.WithValue(ctx, compensationKey{}, true)
+}
+
+func isCompensating(ctx context.Context) bool {
+	v, _ := ctx.Value(compensationKey{}).(bool)
+	return v
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// circuitBreaker tracks consecutive failures of one service.
+type circuitBreaker struct {
+	service string
+	cfg     config.BreakerConfig
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	inFlight int // trial calls admitted while half-open
+}
+
+func newCircuitBreaker(service string, cfg config.BreakerConfig) *circuitBreaker {
+	return &circuitBreaker{service: service, cfg: cfg, now: time.Now}
+}
+
+// allow reports whether a call may proceed, returning a CircuitOpen error if not.
+func (b *circuitBreaker) allow(ctx context.Context) error {
+	if b.cfg.FailureThreshold <= 0 || isCompensating(ctx) {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= time.Duration(b.cfg.OpenSeconds)*time.Second {
+		b.setState(ctx, breakerHalfOpen)
+	}
+	switch b.state {
+	case breakerOpen:
+		b.reject(ctx)
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("circuit open for %s", b.service), CircuitOpenErrorType, nil)
+	case breakerHalfOpen:
+		if b.inFlight >= max(b.cfg.HalfOpenMaxCalls, 1) {
+			b.reject(ctx)
+			return temporal.NewNonRetryableApplicationError(fmt.Sprintf("circuit half-open for %s, trial call in progress", b.service), CircuitOpenErrorType, nil)
+		}
+		b.inFlight++
+	}
+	return nil
+}
+
+// record reports the outcome of an allowed call. Compensations were not
+// admitted by allow and are not counted.
+func (b *circuitBreaker) record(ctx context.Context, failed bool) {
+	if b.cfg.FailureThreshold <= 0 || isCompensating(ctx) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.inFlight--
+		if failed {
+			b.trip(ctx)
+		} else {
+			b.failures = 0
+			b.setState(ctx, breakerClosed)
+		}
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.cfg.FailureThreshold {
+		b.trip(ctx)
+	}
+}
+
+func (b *circuitBreaker) trip(ctx context.Context) {
+	b.openedAt = b.now()
+	b.inFlight = 0
+	b.setState(ctx, breakerOpen)
+}
+
+func (b *circuitBreaker) setState(ctx context.Context, s breakerState) {
+	b.state = s
+	if activity.IsActivity(ctx) {
+		activity.GetMetricsHandler(ctx).WithTags(map[string]string{"service": b.service}).
+			Gauge("saga_circuit_breaker_state").Update(float64(s))
+	}
+}
+
+func (b *circuitBreaker) reject(ctx context.Context) {
+	if activity.IsActivity(ctx) {
+		activity.GetMetricsHandler(ctx).WithTags(map[string]string{"service": b.service}).
+			Counter("saga_circuit_breaker_rejected").Inc(1)
+	}
+}
+
+// isBreakerFailure reports whether a call outcome indicates the service is
+// unhealthy: transport errors and 5xx responses. Client errors (4xx) and
+// cancellation by the caller do not count.
+func isBreakerFailure(err error, status int) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return status >= 500
+}
@@ -0,0 +1,103 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+func Test_CircuitBreaker_Transitions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	b := newCircuitBreaker("api1", config.BreakerConfig{FailureThreshold: 2, OpenSeconds: 10, HalfOpenMaxCalls: 1})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow(ctx); err != nil {
+			t.Fatalf("closed breaker rejected call: %v", err)
+		}
+		b.record(ctx, true)
+	}
+	err := b.allow(ctx)
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != CircuitOpenErrorType || !appErr.NonRetryable() {
+		t.Fatalf("expected non-retryable CircuitOpen error, got %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("half-open breaker rejected trial call: %v", err)
+	}
+	if err := b.allow(ctx); err == nil {
+		t.Fatal("half-open breaker admitted a second concurrent call")
+	}
+	b.record(ctx, true)
+	if b.state != breakerOpen {
+		t.Fatalf("failed trial should reopen, state=%s", b.state)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("half-open breaker rejected trial call: %v", err)
+	}
+	b.record(ctx, false)
+	if b.state != breakerClosed {
+		t.Fatalf("successful trial should close, state=%s", b.state)
+	}
+}
+
+func Test_CircuitBreaker_Disabled(t *testing.T) {
+	b := newCircuitBreaker("api1", config.BreakerConfig{})
+	for i := 0; i < 10; i++ {
+		b.record(context.Background(), true)
+	}
+	if err := b.allow(context.Background()); err != nil {
+		t.Fatalf("disabled breaker rejected call: %v", err)
+	}
+}
+
+func Test_CircuitBreaker_OpenDuringCompensation(t *testing.T) {
+	var deleted int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			atomic.AddInt32(&deleted, 1)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API1BaseURL: srv.URL, API1: config.ServiceConfig{
+		Breaker: config.BreakerConfig{FailureThreshold: 1, OpenSeconds: 60, HalfOpenMaxCalls: 1},
+	}}
+	a, err := NewActivities(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	in := StepInput{BaseURL: srv.URL, Method: http.MethodPut, ResourceID: "r1"}
+	if _, err := a.Step1(ctx, in); err == nil {
+		t.Fatal("expected the failing step to error")
+	}
+	var appErr *temporal.ApplicationError
+	if _, err := a.Step1(ctx, in); !errors.As(err, &appErr) || appErr.Type() != CircuitOpenErrorType {
+		t.Fatalf("expected the breaker to be open, got %v", err)
+	}
+
+	if err := a.RollbackService(ctx, "api1", srv.URL, "r1"); err != nil {
+		t.Fatalf("compensation rejected by open breaker: %v", err)
+	}
+	if n := atomic.LoadInt32(&deleted); n != 1 {
+		t.Fatalf("expected the resource to be deleted once, got %d", n)
+	}
+	if _, err := a.Step1(ctx, in); !errors.As(err, &appErr) || appErr.Type() != CircuitOpenErrorType {
+		t.Fatalf("compensation should not close the breaker, got %v", err)
+	}
+}
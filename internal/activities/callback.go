@@ -0,0 +1,133 @@
+package activities
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// CallbackPath is the cmd/api route partners call to complete an async
+// step, followed by the encoded task token.
+const CallbackPath = "/callbacks/"
+
+// CallbackFailedErrorType is the application error type of a step the
+// partner reported as failed.
+const CallbackFailedErrorType = "CallbackFailed"
+
+// SealTaskToken encrypts and authenticates an activity task token with a
+// key derived from secret, for use in a URL path. Partners cannot read the
+// workflow behind a sealed token or forge one for another step.
+func SealTaskToken(secret string, token []byte) (string, error) {
+	aead, err := callbackCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, token, nil)), nil
+}
+
+// OpenTaskToken reverses SealTaskToken, failing for tokens not sealed with secret.
+func OpenTaskToken(secret, sealed string) ([]byte, error) {
+	aead, err := callbackCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, errors.New("malformed callback token")
+	}
+	token, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("invalid callback token")
+	}
+	return token, nil
+}
+
+func callbackCipher(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("CALLBACK_SECRET is not set")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CallbackRequest is the webhook body a partner posts to the callback URL.
+type CallbackRequest struct {
+	// Status is "completed" (default) or "failed".
+	Status string         `json:"status"`
+	ID     string         `json:"id,omitempty"`
+	Data   map[string]any `json:"data,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Outcome converts the webhook into the result or error of the pending step.
+func (r CallbackRequest) Outcome() (StepResult, error) {
+	switch strings.ToLower(r.Status) {
+	case "", "completed", "succeeded", "success":
+		return StepResult{ResourceID: r.ID, Body: r.Data}, nil
+	case "failed", "error":
+		msg := r.Error
+		if msg == "" {
+			msg = "partner reported failure"
+		}
+		return StepResult{}, temporal.NewNonRetryableApplicationError(msg, CallbackFailedErrorType, nil, r.Data)
+	}
+	return StepResult{}, fmt.Errorf("unknown callback status: %s", r.Status)
+}
+
+// submitAsync sends the step request with a callback URL in payload meta and
+// leaves the activity pending; cmd/api completes it when the partner calls
+// back. If no callback arrives within the step timeout the saga compensates.
+func (c *ExternalClient) submitAsync(ctx context.Context, in StepInput) (StepResult, error) {
+	token, err := SealTaskToken(c.cfg.CallbackSecret, activity.GetInfo(ctx).TaskToken)
+	if err != nil {
+		return StepResult{}, temporal.NewNonRetryableApplicationError(fmt.Sprintf("%s: callback url: %v", c.service, err), "CallbackConfig", nil)
+	}
+	meta := map[string]any{}
+	for k, v := range in.Payload.Meta {
+		meta[k] = v
+	}
+	meta["callback_url"] = strings.TrimRight(c.cfg.CallbackBaseURL, "/") + CallbackPath + token
+	in.Payload.Meta = meta
+
+	url, body, err := requestFor(in)
+	if err != nil {
+		return StepResult{}, err
+	}
+	resp, err := c.do(ctx, in.Method, url, body)
+	if err != nil {
+		return StepResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return StepResult{}, fmt.Errorf("external API error: %d %s", resp.StatusCode, string(b))
+	}
+	return StepResult{}, activity.ErrResultPending
+}
+
+// runStep executes a step synchronously, or submits it for async completion
+// when the workflow asked for a callback.
+func (c *ExternalClient) runStep(ctx context.Context, in StepInput) (StepResult, error) {
+	if in.Callback && !c.cfg.MockMode && activity.IsActivity(ctx) {
+		return c.submitAsync(ctx, in)
+	}
+	return c.crudOperation(ctx, in)
+}
@@ -0,0 +1,167 @@
+package activities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette modes for Config.CassetteMode.
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// interaction is one recorded request/response pair.
+type interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    string            `json:"body,omitempty"`
+	} `json:"response"`
+}
+
+// recordedHeaders are the response headers the client acts on. Request
+// headers are never stored, so credentials stay out of cassettes.
+var recordedHeaders = []string{"Content-Type", "Location", "Retry-After"}
+
+// cassetteTransport records the HTTP exchanges of one service to a JSON file,
+// or serves them back without touching the network. Replay matches method,
+// URL and body, handing out identical requests in recorded order.
+type cassetteTransport struct {
+	mode string
+	path string
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+func newCassetteTransport(mode, dir, service string, next http.RoundTripper) (http.RoundTripper, error) {
+	t := &cassetteTransport{mode: mode, path: filepath.Join(dir, service+".json"), next: next}
+	switch mode {
+	case "", CassetteOff:
+		return next, nil
+	case CassetteRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	case CassetteReplay:
+		b, err := os.ReadFile(t.path)
+		if err != nil {
+			return nil, fmt.Errorf("load cassette: %w", err)
+		}
+		if err := json.Unmarshal(b, &t.interactions); err != nil {
+			return nil, fmt.Errorf("parse cassette %s: %w", t.path, err)
+		}
+		t.used = make([]bool, len(t.interactions))
+	default:
+		return nil, fmt.Errorf("unsupported cassette mode: %s", mode)
+	}
+	return t, nil
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if t.mode == CassetteReplay {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+func (t *cassetteTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, in := range t.interactions {
+		if t.used[i] || in.Request.Method != req.Method || in.Request.URL != req.URL.String() || in.Request.Body != string(body) {
+			continue
+		}
+		t.used[i] = true
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(bytes.NewReader([]byte(in.Response.Body))),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}
+		for k, v := range in.Response.Headers {
+			resp.Header.Set(k, v)
+		}
+		return resp, nil
+	}
+	return nil, errors.New("cassette " + t.path + " has no unused interaction for " + req.Method + " " + req.URL.String())
+}
+
+func (t *cassetteTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var in interaction
+	in.Request.Method = req.Method
+	in.Request.URL = req.URL.String()
+	in.Request.Body = string(body)
+	in.Response.Status = resp.StatusCode
+	in.Response.Body = string(respBody)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if in.Response.Headers == nil {
+				in.Response.Headers = map[string]string{}
+			}
+			in.Response.Headers[h] = v
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, in)
+	if err := t.save(); err != nil {
+		return nil, fmt.Errorf("save cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// save rewrites the cassette atomically so a crash never leaves it truncated.
+func (t *cassetteTransport) save() error {
+	b, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
@@ -0,0 +1,112 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+)
+
+func Test_Cassette_RecordThenReplay(t *testing.T) {
+	var created int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			created++
+			_ = json.NewEncoder(w).Encode(map[string]any{"_id": "rec-1", "n": created})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	cfg := config.Config{HTTPTimeoutSeconds: 2, CassetteMode: CassetteRecord, CassetteDir: t.TempDir(), API1: config.ServiceConfig{
+		Auth: config.AuthConfig{Type: "bearer", Token: "secret"},
+	}}
+	in := StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost, Payload: RequestPayload{Operation: "step1", Data: map[string]any{"k": "v"}}}
+
+	rec, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rec.crudOperation(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.rollback(context.Background(), in.BaseURL, want.ResourceID); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	cfg.CassetteMode = CassetteReplay
+	rep, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rep.crudOperation(context.Background(), in)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if got.ResourceID != "rec-1" || got.Body["n"] != want.Body["n"] {
+		t.Fatalf("replay returned %+v, recorded %+v", got, want)
+	}
+	if err := rep.rollback(context.Background(), in.BaseURL, got.ResourceID); err != nil {
+		t.Fatalf("replay rollback failed: %v", err)
+	}
+	// Each recorded interaction is served once.
+	if _, err := rep.crudOperation(context.Background(), in); err == nil {
+		t.Fatal("expected replay to fail once the cassette is exhausted")
+	}
+}
+
+func Test_Cassette_KeepsOAuth2SecretsOut(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "issued-access-token", "expires_in": 3600})
+	}))
+	var auths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"_id": "rec-1"})
+	}))
+	dir := t.TempDir()
+	cfg := config.Config{HTTPTimeoutSeconds: 2, CassetteMode: CassetteRecord, CassetteDir: dir, API1: config.ServiceConfig{
+		Auth: config.AuthConfig{Type: "oauth2", TokenURL: tokenSrv.URL, ClientID: "cassette-client", ClientSecret: "client-secret-value"},
+	}}
+	in := StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost, Payload: RequestPayload{Operation: "step1", Data: map[string]any{"k": "v"}}}
+
+	rec, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.crudOperation(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if len(auths) != 1 || auths[0] != "Bearer issued-access-token" {
+		t.Fatalf("unexpected authorization: %+v", auths)
+	}
+	srv.Close()
+	tokenSrv.Close()
+
+	b, err := os.ReadFile(filepath.Join(dir, "api1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"issued-access-token", "client-secret-value", tokenSrv.URL} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	// Replay needs neither the token endpoint nor the service.
+	cfg.CassetteMode = CassetteReplay
+	rep, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rep.crudOperation(context.Background(), in); err != nil || got.ResourceID != "rec-1" {
+		t.Fatalf("replay returned %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,58 @@
+package activities
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.temporal.io/sdk/temporal"
+)
+
+// ContractViolationErrorType is the application error type of a step whose
+// response did not match the service's declared schema. Retrying would get
+// the same shape back, so the error is non-retryable.
+const ContractViolationErrorType = "ContractViolation"
+
+// responseSchemas holds the compiled response schemas of a service; nil
+// entries are not validated.
+type responseSchemas struct {
+	create *jsonschema.Schema
+	update *jsonschema.Schema
+}
+
+func newResponseSchemas(cfg config.SchemaConfig) (responseSchemas, error) {
+	var s responseSchemas
+	var err error
+	if cfg.CreateFile != "" {
+		if s.create, err = jsonschema.Compile(cfg.CreateFile); err != nil {
+			return s, fmt.Errorf("compile create response schema: %w", err)
+		}
+	}
+	if cfg.UpdateFile != "" {
+		if s.update, err = jsonschema.Compile(cfg.UpdateFile); err != nil {
+			return s, fmt.Errorf("compile update response schema: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// forMethod returns the schema responses to method must satisfy, if any.
+func (s responseSchemas) forMethod(method string) *jsonschema.Schema {
+	switch method {
+	case http.MethodPost:
+		return s.create
+	case http.MethodPut, http.MethodPatch:
+		return s.update
+	}
+	return nil
+}
+
+// contractViolation builds the non-retryable error for a bad response. The
+// call itself succeeded, so the error carries a StepResult with the resource
+// id the response named, letting the workflow compensate it.
+func contractViolation(service, method, resourceID string, err error) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("%s %s response violates contract: %v", service, method, err),
+		ContractViolationErrorType, err, StepResult{ResourceID: resourceID})
+}
@@ -0,0 +1,75 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+func Test_CrudOperation_ValidatesResponseSchema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "create.json")
+	if err := os.WriteFile(schema, []byte(`{
+		"type": "object",
+		"required": ["_id", "total"],
+		"properties": {"_id": {"type": "string"}, "total": {"type": "number"}}
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c, err := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{
+		Schema: config.SchemaConfig{CreateFile: schema},
+	}}, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost, Payload: RequestPayload{Operation: "step1"}}
+
+	body = `{"_id": "r1", "total": 10}`
+	out, err := c.crudOperation(context.Background(), in)
+	if err != nil || out.ResourceID != "r1" {
+		t.Fatalf("valid response rejected: %+v %v", out, err)
+	}
+
+	for name, b := range map[string]string{
+		"missing field": `{"_id": "r1"}`,
+		"wrong type":    `{"_id": "r1", "total": "10"}`,
+		"not json":      `<html>oops</html>`,
+	} {
+		body = b
+		_, err := c.crudOperation(context.Background(), in)
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || appErr.Type() != ContractViolationErrorType || !appErr.NonRetryable() {
+			t.Fatalf("%s: expected non-retryable contract violation, got %v", name, err)
+		}
+		// The id of what was created travels with the error so it can be compensated
+		var created StepResult
+		if name != "not json" && (appErr.Details(&created) != nil || created.ResourceID != "r1") {
+			t.Fatalf("%s: expected the created id in the details, got %+v", name, created)
+		}
+	}
+
+	// Updates are not covered by the create schema
+	body = `{}`
+	if _, err := c.crudOperation(context.Background(), StepInput{BaseURL: in.BaseURL, Method: http.MethodPatch, ResourceID: "r1"}); err != nil {
+		t.Fatalf("update without schema failed: %v", err)
+	}
+}
+
+func Test_NewExternalClient_InvalidSchema(t *testing.T) {
+	cfg := config.Config{API2: config.ServiceConfig{Schema: config.SchemaConfig{UpdateFile: filepath.Join(t.TempDir(), "missing.json")}}}
+	if _, err := NewExternalClient(cfg, "api2"); err == nil {
+		t.Fatal("expected error for missing schema file")
+	}
+}
@@ -7,27 +7,68 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
 )
 
 type ExternalClient struct {
+	serviceGuards
 	httpClient *http.Client
 	cfg        config.Config
+	service    string
+	scenario   Scenario // mock mode behaviors
+	schemas    responseSchemas
 }
 
-func NewExternalClient(cfg config.Config) *ExternalClient {
-	return &ExternalClient{
-		httpClient: &http.Client{Timeout: cfg.HTTPTimeout()},
-		cfg:        cfg,
+// NewExternalClient returns a client for the named service ("api1", "api2",
+// "api3"), using the TLS and authentication settings of that service. The
+// client owns its transport, so it should be reused across calls.
+func NewExternalClient(cfg config.Config, service string) (*ExternalClient, error) {
+	svc := cfg.Service(service)
+	tc, err := newTLSConfig(svc.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	base := newTransport(svc.HTTP, tc)
+	rt, err := newCassetteTransport(cfg.CassetteMode, cfg.CassetteDir, service, base)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	hc := &http.Client{Timeout: cfg.HTTPTimeout(), Transport: rt}
+	// Token requests bypass the cassette so issued tokens and client
+	// credentials are never recorded; replayed calls need no credentials.
+	guards, err := newServiceGuards(cfg, service, &http.Client{Timeout: cfg.HTTPTimeout(), Transport: base})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CassetteMode == CassetteReplay {
+		guards.auth = noAuth{}
 	}
+	scenario, err := mockScenario(cfg)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := newResponseSchemas(svc.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	return &ExternalClient{
+		serviceGuards: guards,
+		httpClient:    hc,
+		cfg:           cfg,
+		service:       service,
+		scenario:      scenario,
+		schemas:       schemas,
+	}, nil
 }
 
 type RequestPayload struct {
-	Operation string                 `json:"operation"`
-	Data      map[string]any         `json:"data"`
-	Meta      map[string]any         `json:"meta,omitempty"`
+	Operation string         `json:"operation"`
+	Data      map[string]any `json:"data"`
+	Meta      map[string]any `json:"meta,omitempty"`
 }
 
 type ResponsePayload struct {
@@ -43,84 +84,186 @@ type StepInput struct {
 	Method     string         `json:"method"`
 	ResourceID string         `json:"resource_id,omitempty"`
 	Payload    RequestPayload `json:"payload"`
+	// Callback submits the step and leaves it pending until the partner
+	// calls back. The workflow sets it, so it agrees with the step's timeouts.
+	Callback bool `json:"callback,omitempty"`
 }
 
+// StepResult is returned to the workflow. Body holds the decoded response so
+// later steps can reference it in their payload templates.
 type StepResult struct {
-	ResourceID string `json:"resource_id"`
+	ResourceID string         `json:"resource_id"`
+	Body       map[string]any `json:"body,omitempty"`
 }
 
 // callExternal executes the HTTP call based on StepInput.Method.
 func (c *ExternalClient) crudOperation(ctx context.Context, in StepInput) (StepResult, error) {
 	var result StepResult
 
-	// Mock mode: simulate success
+	// Mock mode: simulate the call, following the scenario if one is configured
 	if c.cfg.MockMode {
-		if in.Method == http.MethodPost {
-			result.ResourceID = fmt.Sprintf("mock-%s-%d", in.Payload.Operation, time.Now().Unix())
-			return result, nil
+		return c.scenario.mockStep(ctx, c.service, in)
+	}
+
+	url, body, err := requestFor(in)
+	if err != nil {
+		return result, err
+	}
+
+	// Heartbeat for the whole call, including reading the body and polling.
+	stop := startHeartbeat(ctx, in.Payload.Operation)
+	defer stop()
+
+	resp, err := c.do(ctx, in.Method, url, body)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusAccepted {
+		if resp, err = c.awaitCompletion(ctx, url, resp); err != nil {
+			return result, err
+		}
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return result, fmt.Errorf("external API error: %d %s", resp.StatusCode, string(b))
+	}
+	switch in.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		// Bodies are optional unless the service declares a response schema.
+		var raw any
+		decodeErr := json.NewDecoder(resp.Body).Decode(&raw)
+		if out, ok := raw.(map[string]any); ok {
+			result.ResourceID = resourceID(out)
+			result.Body = out
 		}
+		if result.ResourceID == "" {
+			result.ResourceID = in.ResourceID
+		}
+		if schema := c.schemas.forMethod(in.Method); schema != nil {
+			if decodeErr != nil {
+				return StepResult{}, contractViolation(c.service, in.Method, result.ResourceID, fmt.Errorf("invalid JSON body: %w", decodeErr))
+			}
+			if err := schema.Validate(raw); err != nil {
+				return StepResult{}, contractViolation(c.service, in.Method, result.ResourceID, err)
+			}
+		}
+	case http.MethodGet:
+		// GET steps read data for later steps, so the body must be usable.
+		var out map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return result, fmt.Errorf("decode GET response: %w", err)
+		}
+		result.ResourceID = in.ResourceID
+		result.Body = out
+	case http.MethodDelete:
+		result.ResourceID = in.ResourceID
+	}
+	return result, nil
+}
+
+// mockResult simulates a successful step: creates get a synthetic id and
+// the payload data is echoed back as the body.
+func mockResult(in StepInput) StepResult {
+	var result StepResult
+	if in.Method == http.MethodPost {
+		result.ResourceID = fmt.Sprintf("mock-%s-%d", in.Payload.Operation, time.Now().Unix())
+	} else {
 		result.ResourceID = in.ResourceID
-		return result, nil
 	}
+	if in.Method != http.MethodDelete {
+		result.Body = map[string]any{}
+		for k, v := range in.Payload.Data {
+			result.Body[k] = v
+		}
+		result.Body["id"] = result.ResourceID
+	}
+	return result
+}
 
-	var url string
-	var body []byte
+// requestFor maps a step onto the URL and body of its HTTP request.
+func requestFor(in StepInput) (url string, body []byte, err error) {
 	switch in.Method {
 	case http.MethodPost:
 		url = in.BaseURL + "/create"
 		body, _ = json.Marshal(in.Payload)
-	case http.MethodDelete:
+	case http.MethodGet, http.MethodDelete:
 		if in.ResourceID == "" {
-			return result, fmt.Errorf("resource_id required for DELETE")
+			return "", nil, fmt.Errorf("resource_id required for %s", in.Method)
 		}
 		url = fmt.Sprintf("%s/%s", in.BaseURL, in.ResourceID)
-	case http.MethodPut:
+	case http.MethodPut, http.MethodPatch:
 		if in.ResourceID == "" {
-			return result, fmt.Errorf("resource_id required for PUT")
+			return "", nil, fmt.Errorf("resource_id required for %s", in.Method)
 		}
 		url = fmt.Sprintf("%s/%s", in.BaseURL, in.ResourceID)
 		body, _ = json.Marshal(in.Payload)
 	default:
-		return result, fmt.Errorf("unsupported method: %s", in.Method)
+		return "", nil, fmt.Errorf("unsupported method: %s", in.Method)
+	}
+	return url, body, nil
+}
+
+// resourceID extracts the resource id from a response, supporting the
+// common `id`, `_id` and `ID` keys.
+func resourceID(out map[string]any) string {
+	for _, k := range []string{"id", "_id", "ID"} {
+		if v, ok := out[k].(string); ok {
+			return v
+		}
 	}
+	return ""
+}
+
+// Execute implements Transport.
+func (c *ExternalClient) Execute(ctx context.Context, in StepInput) (StepResult, error) {
+	return c.runStep(ctx, in)
+}
+
+// Compensate implements Transport by deleting the resource.
+func (c *ExternalClient) Compensate(ctx context.Context, baseURL, id string) error {
+	if c.cfg.MockMode {
+		return c.scenario.mockCompensate(ctx, c.service)
+	}
+	return c.rollback(ctx, baseURL, id)
+}
 
-	req, err := http.NewRequestWithContext(ctx, in.Method, url, bytes.NewReader(body))
+// do sends an authenticated request to the service.
+func (c *ExternalClient) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
 	if err != nil {
-		return result, err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	// Authenticate after the wait so signature timestamps are fresh when sent.
+	if err := c.auth.authenticate(req, body); err != nil {
+		return nil, fmt.Errorf("authenticate %s request: %w", c.service, err)
+	}
+	if err := c.breaker.allow(ctx); err != nil {
+		return nil, err
+	}
 	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return result, err
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return result, fmt.Errorf("external API error: %d %s", resp.StatusCode, string(b))
+	c.breaker.record(ctx, isBreakerFailure(err, status))
+	if err != nil {
+		return nil, err
 	}
-	if in.Method == http.MethodPost || in.Method == http.MethodPut {
-		var out map[string]any
-		if err := json.NewDecoder(resp.Body).Decode(&out); err == nil {
-			if v, ok := out["id"].(string); ok {
-				result.ResourceID = v
-			} else if v, ok := out["_id"].(string); ok {
-				result.ResourceID = v
-			} else if v, ok := out["ID"].(string); ok {
-				result.ResourceID = v
-			}
-		}
-	} else if in.Method == http.MethodDelete {
-		result.ResourceID = in.ResourceID
+	// A rejected OAuth2 token may have been revoked early; fetch a fresh one on retry.
+	if o, ok := c.auth.(*oauth2Auth); ok && resp.StatusCode == http.StatusUnauthorized {
+		o.invalidate()
 	}
-	return result, nil
+	return resp, nil
 }
 
 func (c *ExternalClient) rollback(ctx context.Context, baseURL, id string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", baseURL, id), nil)
-	if err != nil {
-		return err
-	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", baseURL, id), nil)
 	if err != nil {
 		return err
 	}
@@ -136,26 +279,86 @@ func (c *ExternalClient) rollback(ctx context.Context, baseURL, id string) error
 
 type Activities struct {
 	Cfg config.Config
+
+	mu         sync.Mutex
+	transports map[string]Transport
+}
+
+// serviceNames lists the downstream services steps and rollbacks call.
+var serviceNames = []string{"api1", "api2", "api3"}
+
+// NewActivities builds the activities with a long-lived, pooled transport
+// per service, so configuration errors surface at worker start-up rather than
+// on the first saga.
+func NewActivities(cfg config.Config) (*Activities, error) {
+	a := &Activities{Cfg: cfg}
+	for _, name := range serviceNames {
+		if _, err := a.transport(name); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// transport returns the shared transport for service, creating it on first use.
+func (a *Activities) transport(service string) (Transport, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if t, ok := a.transports[service]; ok {
+		return t, nil
+	}
+	t, err := NewTransport(a.Cfg, service)
+	if err != nil {
+		return nil, err
+	}
+	if a.transports == nil {
+		a.transports = map[string]Transport{}
+	}
+	a.transports[service] = t
+	return t, nil
 }
 
 func (a *Activities) Step1(ctx context.Context, in StepInput) (StepResult, error) {
-	client := NewExternalClient(a.Cfg)
-	return client.crudOperation(ctx, in)
+	t, err := a.transport("api1")
+	if err != nil {
+		return StepResult{}, err
+	}
+	return t.Execute(ctx, in)
 }
 
 func (a *Activities) Step2(ctx context.Context, in StepInput) (StepResult, error) {
-	client := NewExternalClient(a.Cfg)
-	return client.crudOperation(ctx, in)
+	t, err := a.transport("api2")
+	if err != nil {
+		return StepResult{}, err
+	}
+	return t.Execute(ctx, in)
 }
 
 func (a *Activities) Step3(ctx context.Context, in StepInput) (StepResult, error) {
-	client := NewExternalClient(a.Cfg)
-	return client.crudOperation(ctx, in)
+	t, err := a.transport("api3")
+	if err != nil {
+		return StepResult{}, err
+	}
+	return t.Execute(ctx, in)
 }
 
+// Rollback deletes resource id at baseURL. It keeps the signature of the
+// activities scheduled before rollbacks named their service, which it now
+// finds from baseURL; new sagas use RollbackService.
 func (a *Activities) Rollback(ctx context.Context, baseURL, id string) error {
-	client := NewExternalClient(a.Cfg)
-	return client.rollback(ctx, baseURL, id)
+	for _, service := range serviceNames {
+		if a.Cfg.BaseURL(service) == baseURL {
+			return a.RollbackService(ctx, service, baseURL, id)
+		}
+	}
+	return temporal.NewNonRetryableApplicationError(fmt.Sprintf("no service has base url %s", baseURL), "UnknownService", nil)
 }
 
-
+// RollbackService deletes resource id created on the named service.
+func (a *Activities) RollbackService(ctx context.Context, service, baseURL, id string) error {
+	t, err := a.transport(service)
+	if err != nil {
+		return err
+	}
+	return t.Compensate(compensating(ctx), baseURL, id)
+}
@@ -0,0 +1,184 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+func Test_CrudOperation_GetReturnsBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api1/r1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"_id": "r1", "name": "n"})
+	}))
+	defer srv.Close()
+
+	c, _ := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	out, err := c.crudOperation(context.Background(), StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodGet, ResourceID: "r1"})
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if out.ResourceID != "r1" || out.Body["name"] != "n" {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+}
+
+func Test_CrudOperation_PatchSendsPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/api1/r1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var p RequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Data["k"] != "v" {
+			t.Errorf("unexpected payload %+v (%v)", p, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, _ := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	out, err := c.crudOperation(context.Background(), StepInput{
+		BaseURL:    srv.URL + "/api1",
+		Method:     http.MethodPatch,
+		ResourceID: "r1",
+		Payload:    RequestPayload{Operation: "step1", Data: map[string]any{"k": "v"}},
+	})
+	if err != nil {
+		t.Fatalf("patch failed: %v", err)
+	}
+	if out.ResourceID != "r1" {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+}
+
+func Test_CrudOperation_RequiresResourceID(t *testing.T) {
+	c, _ := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	for _, m := range []string{http.MethodGet, http.MethodPatch} {
+		if _, err := c.crudOperation(context.Background(), StepInput{BaseURL: "http://unused", Method: m}); err == nil {
+			t.Fatalf("%s without resource_id should fail", m)
+		}
+	}
+}
+
+func Test_RateLimit_SpacesRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{RateLimit: config.RateLimitConfig{RPS: 20, Burst: 1}}}
+	c, err := NewExternalClient(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := c.rollback(context.Background(), srv.URL, "r1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 5 calls at 20/s with burst 1 need at least 4 intervals of 50ms.
+	if d := time.Since(start); d < 190*time.Millisecond {
+		t.Fatalf("requests were not rate limited, took %s", d)
+	}
+}
+
+func Test_CrudOperation_PollsAcceptedOperation(t *testing.T) {
+	var polls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api1/create", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/ops/42")
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/ops/42", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "running"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "completed", "result": map[string]any{"id": "r9"}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, _ := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	out, err := c.crudOperation(context.Background(), StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost})
+	if err != nil {
+		t.Fatalf("async create failed: %v", err)
+	}
+	if out.ResourceID != "r9" || atomic.LoadInt32(&polls) != 3 {
+		t.Fatalf("unexpected result %+v after %d polls", out, polls)
+	}
+}
+
+func Test_CrudOperation_PollsOnlyTheServiceOrigin(t *testing.T) {
+	var leaked int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&leaked, 1)
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", other.URL+"/ops/42")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{Auth: config.AuthConfig{Type: "bearer", Token: "secret"}}}
+	c, _ := NewExternalClient(cfg, "api1")
+	_, err := c.crudOperation(context.Background(), StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Fatalf("expected a non-retryable error for a foreign status location, got %v", err)
+	}
+	if n := atomic.LoadInt32(&leaked); n != 0 {
+		t.Fatalf("polled the foreign host %d times", n)
+	}
+}
+
+func Test_CrudOperation_PollIntervalHasAMinimum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Location", "/api1/ops/1")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "r9"})
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{Poll: config.PollConfig{IntervalSeconds: 0}}}
+	c, _ := NewExternalClient(cfg, "api1")
+	start := time.Now()
+	if _, err := c.crudOperation(context.Background(), StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < minPollInterval {
+		t.Fatalf("polled after %s, before the minimum interval", d)
+	}
+}
+
+func Test_CrudOperation_AsyncFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status_url": "/api1/ops/1"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "failed", "message": "boom"})
+	}))
+	defer srv.Close()
+
+	c, _ := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2}, "api1")
+	if _, err := c.crudOperation(context.Background(), StepInput{BaseURL: srv.URL + "/api1", Method: http.MethodPost}); err == nil {
+		t.Fatal("expected failed async operation to fail the step")
+	}
+}
@@ -0,0 +1,182 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+// GraphQLErrorType is the application error type of a step whose response
+// carried GraphQL errors.
+const GraphQLErrorType = "GraphQLError"
+
+// graphqlTransport runs steps as GraphQL mutations over the service's HTTP
+// client, so auth, TLS, breaker and rate limit settings apply unchanged.
+type graphqlTransport struct {
+	client *ExternalClient
+	gc     config.GraphQLConfig
+}
+
+func newGraphQLTransport(cfg config.Config, service string) (*graphqlTransport, error) {
+	gc := cfg.Service(service).GraphQL
+	if len(gc.Mutations) == 0 {
+		return nil, fmt.Errorf("%s: graphql transport requires mutations", service)
+	}
+	// Without the new id, a created resource could never be compensated.
+	if gc.Mutations[http.MethodPost] != "" && gc.IDPath == "" {
+		return nil, fmt.Errorf("%s: graphql create mutation requires an id path", service)
+	}
+	c, err := NewExternalClient(cfg, service)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlTransport{client: c, gc: gc}, nil
+}
+
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+type graphqlError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Execute runs the mutation mapped to the step method with variables built
+// from the payload data; the resource id (for updates and deletes) is passed
+// in IDVariable and a created one is read from IDPath.
+func (g *graphqlTransport) Execute(ctx context.Context, in StepInput) (StepResult, error) {
+	if g.client.cfg.MockMode {
+		return g.client.scenario.mockStep(ctx, g.client.service, in)
+	}
+	mutation := g.gc.Mutations[in.Method]
+	if mutation == "" {
+		return StepResult{}, fmt.Errorf("%s: no graphql mutation configured for %s", g.client.service, in.Method)
+	}
+	stop := startHeartbeat(ctx, in.Payload.Operation)
+	defer stop()
+
+	vars := map[string]any{}
+	if g.gc.VariablesKey != "" {
+		vars[g.gc.VariablesKey] = in.Payload.Data
+	} else {
+		for k, v := range in.Payload.Data {
+			vars[k] = v
+		}
+	}
+	if in.ResourceID != "" {
+		vars[g.gc.IDVariable] = in.ResourceID
+	}
+	data, err := g.call(ctx, g.endpoint(in.BaseURL), mutation, vars)
+	if err != nil {
+		return StepResult{}, err
+	}
+	result := StepResult{ResourceID: in.ResourceID, Body: data}
+	if in.Method == http.MethodPost {
+		id, ok := lookupPath(data, g.gc.IDPath).(string)
+		if !ok || id == "" {
+			return StepResult{}, fmt.Errorf("graphql response has no resource id at %s", g.gc.IDPath)
+		}
+		result.ResourceID = id
+	}
+	return result, nil
+}
+
+// Compensate runs the compensation mutation (by default the DELETE mapping)
+// with the id in CompensateIDVariable.
+func (g *graphqlTransport) Compensate(ctx context.Context, baseURL, id string) error {
+	if g.client.cfg.MockMode {
+		return g.client.scenario.mockCompensate(ctx, g.client.service)
+	}
+	mutation := g.gc.CompensateMutation
+	if mutation == "" {
+		mutation = g.gc.Mutations[http.MethodDelete]
+	}
+	if mutation == "" {
+		return fmt.Errorf("%s: no graphql compensation mutation configured", g.client.service)
+	}
+	if _, err := g.call(ctx, g.endpoint(baseURL), mutation, map[string]any{g.gc.CompensateIDVariable: id}); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+func (g *graphqlTransport) endpoint(baseURL string) string {
+	if g.gc.Endpoint != "" {
+		return g.gc.Endpoint
+	}
+	return baseURL
+}
+
+// call posts one GraphQL document. Unlike plain HTTP steps, a 200 response
+// with a non-empty `errors` array is a failure; it is retried only when every
+// error carries a transient extensions.code, since the rest (validation,
+// authorization, business rules) fail the same way on every attempt.
+func (g *graphqlTransport) call(ctx context.Context, endpoint, query string, vars map[string]any) (map[string]any, error) {
+	body, err := json.Marshal(graphqlRequest{Query: query, Variables: vars})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.client.do(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("external API error: %d %s", resp.StatusCode, string(b))
+	}
+	var out graphqlResponse
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("decode graphql response: %w", err)
+	}
+	if len(out.Errors) > 0 {
+		msgs := make([]string, len(out.Errors))
+		for i, e := range out.Errors {
+			msgs[i] = e.Message
+		}
+		if g.transient(out.Errors) {
+			return nil, temporal.NewApplicationError(strings.Join(msgs, "; "), GraphQLErrorType, out.Errors)
+		}
+		return nil, temporal.NewNonRetryableApplicationError(strings.Join(msgs, "; "), GraphQLErrorType, nil, out.Errors)
+	}
+	return out.Data, nil
+}
+
+// transient reports whether all errors have an extensions.code listed in
+// TransientCodes.
+func (g *graphqlTransport) transient(errs []graphqlError) bool {
+	for _, e := range errs {
+		code, _ := e.Extensions["code"].(string)
+		if code == "" || !slices.Contains(g.gc.TransientCodes, code) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPath walks a dotted path through nested JSON objects.
+func lookupPath(v any, path string) any {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
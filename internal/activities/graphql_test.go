@@ -0,0 +1,148 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+func graphqlCfg(endpoint string) config.Config {
+	return config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{
+		Transport: "graphql",
+		GraphQL: config.GraphQLConfig{
+			Endpoint: endpoint,
+			Mutations: map[string]string{
+				http.MethodPost: "mutation($input: OrderInput!) { createOrder(input: $input) { id } }",
+				http.MethodPut:  "mutation($orderId: ID!, $input: OrderInput!) { updateOrder(id: $orderId, input: $input) { id } }",
+			},
+			VariablesKey:         "input",
+			IDVariable:           "orderId",
+			IDPath:               "createOrder.id",
+			CompensateMutation:   "mutation($id: ID!) { cancelOrder(id: $id) { id } }",
+			CompensateIDVariable: "id",
+			TransientCodes:       []string{"UNAVAILABLE"},
+		},
+	}}
+}
+
+func Test_GraphQL_MutationAndCompensation(t *testing.T) {
+	var cancelled string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "cancelOrder") {
+			cancelled, _ = req.Variables["id"].(string)
+			_, _ = w.Write([]byte(`{"data":{"cancelOrder":{"id":"o-7"}}}`))
+			return
+		}
+		input, _ := req.Variables["input"].(map[string]any)
+		if input["customer"] != "acme" {
+			t.Errorf("unexpected variables: %+v", req.Variables)
+		}
+		_, _ = w.Write([]byte(`{"data":{"createOrder":{"id":"o-7"}}}`))
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport(graphqlCfg(srv.URL), "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tr.Execute(context.Background(), StepInput{Method: http.MethodPost, Payload: RequestPayload{Data: map[string]any{"customer": "acme"}}})
+	if err != nil {
+		t.Fatalf("mutation failed: %v", err)
+	}
+	if out.ResourceID != "o-7" {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if err := tr.Compensate(context.Background(), "", "o-7"); err != nil || cancelled != "o-7" {
+		t.Fatalf("compensation failed: %v (cancelled %q)", err, cancelled)
+	}
+}
+
+func Test_GraphQL_ErrorsIn200AreFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"createOrder":null},"errors":[{"message":"customer is blocked","path":["createOrder"]}]}`))
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport(graphqlCfg(srv.URL), "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tr.Execute(context.Background(), StepInput{Method: http.MethodPost, Payload: RequestPayload{Data: map[string]any{"customer": "acme"}}})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != GraphQLErrorType || !strings.Contains(appErr.Error(), "customer is blocked") {
+		t.Fatalf("expected GraphQLError, got %v", err)
+	}
+	if !appErr.NonRetryable() {
+		t.Fatal("expected a business error to be non-retryable")
+	}
+}
+
+func Test_GraphQL_TransientErrorsRetry(t *testing.T) {
+	for body, retryable := range map[string]bool{
+		`{"errors":[{"message":"try later","extensions":{"code":"UNAVAILABLE"}}]}`:                                                                true,
+		`{"errors":[{"message":"try later","extensions":{"code":"UNAVAILABLE"}},{"message":"bad input","extensions":{"code":"BAD_USER_INPUT"}}]}`: false,
+		`{"errors":[{"message":"forbidden","extensions":{"code":"FORBIDDEN"}}]}`:                                                                  false,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		tr, err := NewTransport(graphqlCfg(srv.URL), "api1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tr.Execute(context.Background(), StepInput{Method: http.MethodPost, Payload: RequestPayload{Data: map[string]any{}}})
+		srv.Close()
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || appErr.NonRetryable() == retryable {
+			t.Fatalf("%s: expected retryable=%v, got %v", body, retryable, err)
+		}
+	}
+}
+
+func Test_GraphQL_ResourceIDUsesConfiguredVariable(t *testing.T) {
+	var query string
+	var vars map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		query, vars = req.Query, req.Variables
+		_, _ = w.Write([]byte(`{"data":{"updateOrder":{"id":"o-7"}}}`))
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport(graphqlCfg(srv.URL), "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Execute(context.Background(), StepInput{Method: http.MethodPut, ResourceID: "o-7", Payload: RequestPayload{Data: map[string]any{}}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "updateOrder") || vars["orderId"] != "o-7" || vars["id"] != nil {
+		t.Fatalf("unexpected request: %s %+v", query, vars)
+	}
+	// Deletes have no mutation mapped, so they fail instead of running another one.
+	if _, err := tr.Execute(context.Background(), StepInput{Method: http.MethodDelete, ResourceID: "o-7"}); err == nil || !strings.Contains(err.Error(), "no graphql mutation configured for DELETE") {
+		t.Fatalf("expected an unmapped method to fail, got %v", err)
+	}
+}
+
+func Test_GraphQL_CreateRequiresIDPath(t *testing.T) {
+	cfg := graphqlCfg("http://graphql.test")
+	cfg.API1.GraphQL.IDPath = ""
+	if _, err := NewTransport(cfg, "api1"); err == nil {
+		t.Fatal("expected a create mutation without an id path to be rejected")
+	}
+	delete(cfg.API1.GraphQL.Mutations, http.MethodPost)
+	if _, err := NewTransport(cfg, "api1"); err != nil {
+		t.Fatalf("updates alone need no id path: %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcTransport calls unary gRPC methods dynamically: request messages are
+// built from the step's JSON payload and responses are mapped back to JSON,
+// using descriptors from a descriptor set file or server reflection.
+type grpcTransport struct {
+	serviceGuards
+	cfg      config.Config
+	gc       config.GRPCConfig
+	service  string
+	conn     *grpc.ClientConn
+	scenario Scenario
+
+	mu       sync.Mutex
+	set      *protoregistry.Files                      // descriptor set, nil until first resolve
+	services map[string]protoreflect.ServiceDescriptor // resolved services by full name
+}
+
+func newGRPCTransport(cfg config.Config, service string) (*grpcTransport, error) {
+	svc := cfg.Service(service)
+	if svc.GRPC.Target == "" {
+		return nil, fmt.Errorf("%s: grpc transport requires a target", service)
+	}
+	if svc.GRPC.DescriptorSet == "" && !svc.GRPC.Reflection {
+		return nil, fmt.Errorf("%s: grpc transport requires a descriptor set or server reflection", service)
+	}
+	creds := insecure.NewCredentials()
+	if !svc.GRPC.Plaintext {
+		tc, err := newTLSConfig(svc.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", service, err)
+		}
+		creds = credentials.NewTLS(tc)
+	}
+	conn, err := grpc.NewClient(svc.GRPC.Target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	guards, err := newServiceGuards(cfg, service, &http.Client{Timeout: cfg.HTTPTimeout()})
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := mockScenario(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcTransport{
+		serviceGuards: guards,
+		cfg:           cfg,
+		gc:            svc.GRPC,
+		service:       service,
+		conn:          conn,
+		scenario:      scenario,
+		services:      map[string]protoreflect.ServiceDescriptor{},
+	}, nil
+}
+
+// Execute calls the method mapped to the step's HTTP method, with the
+// payload data as the request message and the resource id (if any) in IDField.
+func (g *grpcTransport) Execute(ctx context.Context, in StepInput) (StepResult, error) {
+	if g.cfg.MockMode {
+		return g.scenario.mockStep(ctx, g.service, in)
+	}
+	method := g.gc.Methods[in.Method]
+	if method == "" {
+		return StepResult{}, fmt.Errorf("%s: no grpc method configured for %s", g.service, in.Method)
+	}
+	stop := startHeartbeat(ctx, in.Payload.Operation)
+	defer stop()
+
+	out, err := g.invoke(ctx, method, in.Payload.Data, g.gc.IDField, in.ResourceID)
+	if err != nil {
+		return StepResult{}, err
+	}
+	result := StepResult{ResourceID: in.ResourceID, Body: out}
+	if v, ok := out[g.gc.IDField].(string); ok && v != "" {
+		result.ResourceID = v
+	}
+	return result, nil
+}
+
+// Compensate calls CompensateMethod (or the DELETE mapping) with id in
+// CompensateIDField.
+func (g *grpcTransport) Compensate(ctx context.Context, _ string, id string) error {
+	if g.cfg.MockMode {
+		return g.scenario.mockCompensate(ctx, g.service)
+	}
+	method := g.gc.CompensateMethod
+	if method == "" {
+		method = g.gc.Methods[http.MethodDelete]
+	}
+	if method == "" {
+		return fmt.Errorf("%s: no grpc compensation method configured", g.service)
+	}
+	if _, err := g.invoke(ctx, method, nil, g.gc.CompensateIDField, id); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+// invoke performs one unary call of fullMethod ("pkg.Service/Method").
+func (g *grpcTransport) invoke(ctx context.Context, fullMethod string, data map[string]any, idField, id string) (map[string]any, error) {
+	md, err := g.resolve(ctx, fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	req := dynamicpb.NewMessage(md.Input())
+	if data != nil {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("map payload to %s: %w", md.Input().FullName(), err)
+		}
+	}
+	if id != "" {
+		fd := md.Input().Fields().ByName(protoreflect.Name(idField))
+		if fd == nil || fd.Kind() != protoreflect.StringKind {
+			return nil, fmt.Errorf("%s has no string field %q for the resource id", md.Input().FullName(), idField)
+		}
+		req.Set(fd, protoreflect.ValueOfString(id))
+	}
+	// Marshal once and send those bytes, so signatures cover exactly what goes on the wire.
+	wire, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+	ctx, err = g.withAuth(ctx, fullMethod, wire)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.breaker.allow(ctx); err != nil {
+		return nil, err
+	}
+	resp := dynamicpb.NewMessage(md.Output())
+	err = g.conn.Invoke(ctx, "/"+fullMethod, rawMessage(wire), resp, grpc.ForceCodec(rawRequestCodec{}))
+	g.breaker.record(ctx, isGRPCBreakerFailure(err))
+	if err != nil {
+		return nil, fmt.Errorf("grpc %s: %w", fullMethod, err)
+	}
+
+	b, err := (protojson.MarshalOptions{UseProtoNames: true}).Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// withAuth runs the service's authenticator against a synthetic HTTP request
+// and forwards the resulting headers as gRPC metadata.
+func (g *grpcTransport) withAuth(ctx context.Context, fullMethod string, body []byte) (context.Context, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+fullMethod, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.auth.authenticate(req, body); err != nil {
+		return nil, fmt.Errorf("authenticate %s request: %w", g.service, err)
+	}
+	for k, vs := range req.Header {
+		for _, v := range vs {
+			ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(k), v)
+		}
+	}
+	return ctx, nil
+}
+
+// resolve finds the descriptor of fullMethod. Services are resolved once
+// each: from the descriptor set, or by asking the server's reflection
+// service for the files defining that service.
+func (g *grpcTransport) resolve(ctx context.Context, fullMethod string) (protoreflect.MethodDescriptor, error) {
+	svcName, methodName, ok := strings.Cut(fullMethod, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid grpc method %q, want package.Service/Method", fullMethod)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sd, ok := g.services[svcName]
+	if !ok {
+		files, err := g.descriptors(ctx, svcName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: load descriptors: %w", g.service, err)
+		}
+		d, err := files.FindDescriptorByName(protoreflect.FullName(svcName))
+		if err != nil {
+			return nil, fmt.Errorf("%s: service %s: %w", g.service, svcName, err)
+		}
+		if sd, ok = d.(protoreflect.ServiceDescriptor); !ok {
+			return nil, fmt.Errorf("%s: %s is not a service", g.service, svcName)
+		}
+		g.services[svcName] = sd
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, fmt.Errorf("%s: service %s has no method %s", g.service, svcName, methodName)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s: %s is a streaming method, only unary calls are supported", g.service, fullMethod)
+	}
+	return md, nil
+}
+
+// descriptors returns files defining symbol and its dependencies.
+func (g *grpcTransport) descriptors(ctx context.Context, symbol string) (*protoregistry.Files, error) {
+	if g.gc.DescriptorSet == "" {
+		return fetchDescriptors(ctx, g.conn, symbol)
+	}
+	if g.set == nil {
+		set, err := loadDescriptorSet(g.gc.DescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		g.set = set
+	}
+	return g.set, nil
+}
+
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse descriptor set %s: %w", path, err)
+	}
+	return protodesc.NewFiles(&set)
+}
+
+// fetchDescriptors asks the server's reflection service for the file
+// defining symbol and, transitively, the files it imports.
+func fetchDescriptors(ctx context.Context, conn *grpc.ClientConn, symbol string) (*protoregistry.Files, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	protos := map[string]*descriptorpb.FileDescriptorProto{}
+	request := func(req *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return fmt.Errorf("reflection: %s", e.GetErrorMessage())
+		}
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return err
+			}
+			protos[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	if err := request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}); err != nil {
+		return nil, err
+	}
+	for {
+		missing := ""
+		for _, fd := range protos {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := protos[dep]; !ok {
+					missing = dep
+				}
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if err := request(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: missing},
+		}); err != nil {
+			return nil, err
+		}
+		if _, ok := protos[missing]; !ok {
+			return nil, fmt.Errorf("reflection did not return %s", missing)
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range protos {
+		set.File = append(set.File, fd)
+	}
+	return protodesc.NewFiles(set)
+}
+
+// rawMessage is a request already in protobuf wire format.
+type rawMessage []byte
+
+// rawRequestCodec is the proto codec, except that rawMessage requests are
+// sent as they are.
+type rawRequestCodec struct{}
+
+func (rawRequestCodec) Marshal(v any) ([]byte, error) {
+	if b, ok := v.(rawMessage); ok {
+		return b, nil
+	}
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (rawRequestCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+func (rawRequestCodec) Name() string { return "proto" }
+
+// isGRPCBreakerFailure reports whether a call error means the service is unhealthy.
+func isGRPCBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package activities
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ordersFile describes:
+//
+//	service Orders {
+//	  rpc Create(CreateOrder) returns (Order);
+//	  rpc Cancel(CancelOrder) returns (Order);
+//	}
+func ordersFile() *descriptorpb.FileDescriptorProto {
+	str := func(name string, n int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(n),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("orders/v1/orders.proto"),
+		Package: proto.String("orders.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("CreateOrder"), Field: []*descriptorpb.FieldDescriptorProto{str("customer", 1)}},
+			{Name: proto.String("CancelOrder"), Field: []*descriptorpb.FieldDescriptorProto{str("order_id", 1)}},
+			{Name: proto.String("Order"), Field: []*descriptorpb.FieldDescriptorProto{str("id", 1), str("customer", 2), str("state", 3)}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Orders"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Create"), InputType: proto.String(".orders.v1.CreateOrder"), OutputType: proto.String(".orders.v1.Order")},
+				{Name: proto.String("Cancel"), InputType: proto.String(".orders.v1.CancelOrder"), OutputType: proto.String(".orders.v1.Order")},
+			},
+		}},
+	}
+}
+
+// refundsFile describes, in a file of its own:
+//
+//	service Refunds {
+//	  rpc Refund(RefundRequest) returns (Refund);
+//	}
+func refundsFile() *descriptorpb.FileDescriptorProto {
+	str := func(name string, n int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(n),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("billing/v1/refunds.proto"),
+		Package: proto.String("billing.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("RefundRequest"), Field: []*descriptorpb.FieldDescriptorProto{str("order_id", 1)}},
+			{Name: proto.String("Refund"), Field: []*descriptorpb.FieldDescriptorProto{str("id", 1)}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Refunds"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Refund"), InputType: proto.String(".billing.v1.RefundRequest"), OutputType: proto.String(".billing.v1.Refund")},
+			},
+		}},
+	}
+}
+
+// ordersServer implements Orders and Refunds with dynamic messages and
+// records calls.
+type ordersServer struct {
+	mu         sync.Mutex
+	cancelled  []string
+	refunded   []string
+	tokens     []string
+	signatures []string // "ok" or what was wrong with an X-Signature
+}
+
+// hmacSecret is the secret calls with hmac auth are checked against.
+const hmacSecret = "s"
+
+func startOrdersServer(t *testing.T, fd, refunds protoreflect.FileDescriptor, srv *ordersServer) string {
+	t.Helper()
+	sd := fd.Services().ByName("Orders")
+	handler := func(method protoreflect.MethodDescriptor, impl func(in, out *dynamicpb.Message)) grpc.MethodDesc {
+		return grpc.MethodDesc{
+			MethodName: string(method.Name()),
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := dynamicpb.NewMessage(method.Input())
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				md, _ := metadata.FromIncomingContext(ctx)
+				srv.mu.Lock()
+				srv.tokens = append(srv.tokens, md.Get("authorization")...)
+				if sig := md.Get("x-signature"); len(sig) > 0 {
+					// The signature covers the request in wire format.
+					wire, _ := proto.Marshal(in)
+					full := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+					mac := hmac.New(sha256.New, []byte(hmacSecret))
+					mac.Write([]byte("POST\n" + full + "\n" + md.Get("x-signature-timestamp")[0] + "\n"))
+					mac.Write(wire)
+					if sig[0] == hex.EncodeToString(mac.Sum(nil)) {
+						srv.signatures = append(srv.signatures, "ok")
+					} else {
+						srv.signatures = append(srv.signatures, "mismatch for "+full)
+					}
+				}
+				srv.mu.Unlock()
+				out := dynamicpb.NewMessage(method.Output())
+				impl(in, out)
+				return out, nil
+			},
+		}
+	}
+	field := func(m *dynamicpb.Message, name string) protoreflect.FieldDescriptor {
+		return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	}
+	desc := grpc.ServiceDesc{
+		ServiceName: string(sd.FullName()),
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			handler(sd.Methods().ByName("Create"), func(in, out *dynamicpb.Message) {
+				out.Set(field(out, "id"), protoreflect.ValueOfString("o-1"))
+				out.Set(field(out, "customer"), in.Get(field(in, "customer")))
+				out.Set(field(out, "state"), protoreflect.ValueOfString("CREATED"))
+			}),
+			handler(sd.Methods().ByName("Cancel"), func(in, out *dynamicpb.Message) {
+				id := in.Get(field(in, "order_id")).String()
+				srv.mu.Lock()
+				srv.cancelled = append(srv.cancelled, id)
+				srv.mu.Unlock()
+				out.Set(field(out, "id"), protoreflect.ValueOfString(id))
+				out.Set(field(out, "state"), protoreflect.ValueOfString("CANCELLED"))
+			}),
+		},
+	}
+
+	rd := refunds.Services().ByName("Refunds")
+	refundsDesc := grpc.ServiceDesc{
+		ServiceName: string(rd.FullName()),
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			handler(rd.Methods().ByName("Refund"), func(in, out *dynamicpb.Message) {
+				id := in.Get(field(in, "order_id")).String()
+				srv.mu.Lock()
+				srv.refunded = append(srv.refunded, id)
+				srv.mu.Unlock()
+				out.Set(field(out, "id"), protoreflect.ValueOfString("r-"+id))
+			}),
+		},
+	}
+
+	files := new(protoregistry.Files)
+	for _, f := range []protoreflect.FileDescriptor{fd, refunds} {
+		if err := files.RegisterFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := grpc.NewServer()
+	s.RegisterService(&desc, struct{}{})
+	s.RegisterService(&refundsDesc, struct{}{})
+	reflectionpb.RegisterServerReflectionServer(s, reflection.NewServerV1(reflection.ServerOptions{Services: s, DescriptorResolver: files}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func Test_GRPCTransport_CreateAndCompensate(t *testing.T) {
+	fdp := ordersFile()
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdp := refundsFile()
+	rd, err := protodesc.NewFile(rdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &ordersServer{}
+	addr := startOrdersServer(t, fd, rd, srv)
+
+	setPath := filepath.Join(t.TempDir(), "orders.pb")
+	b, _ := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fdp, rdp}})
+	if err := os.WriteFile(setPath, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, gc := range map[string]config.GRPCConfig{
+		"descriptor set": {DescriptorSet: setPath},
+		"reflection":     {Reflection: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gc.Target = addr
+			gc.Plaintext = true
+			gc.Methods = map[string]string{http.MethodPost: "orders.v1.Orders/Create"}
+			gc.CompensateMethod = "orders.v1.Orders/Cancel"
+			gc.IDField = "id"
+			gc.CompensateIDField = "order_id"
+			cfg := config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{
+				Transport: "grpc",
+				GRPC:      gc,
+				Auth:      config.AuthConfig{Type: "bearer", Token: "t0k"},
+			}}
+			tr, err := NewTransport(cfg, "api1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := tr.Execute(context.Background(), StepInput{
+				Method:  http.MethodPost,
+				Payload: RequestPayload{Operation: "step1", Data: map[string]any{"customer": "acme", "ignored": 1}},
+			})
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			if out.ResourceID != "o-1" || out.Body["customer"] != "acme" || out.Body["state"] != "CREATED" {
+				t.Fatalf("unexpected result: %+v", out)
+			}
+			if err := tr.Compensate(context.Background(), "", out.ResourceID); err != nil {
+				t.Fatalf("compensate failed: %v", err)
+			}
+		})
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.cancelled) != 2 || srv.cancelled[0] != "o-1" {
+		t.Fatalf("unexpected compensations: %+v", srv.cancelled)
+	}
+	if len(srv.tokens) != 4 {
+		t.Fatalf("expected authorization metadata on every call, got %+v", srv.tokens)
+	}
+	for _, tok := range srv.tokens {
+		if tok != "Bearer t0k" {
+			t.Fatalf("unexpected authorization metadata: %q", tok)
+		}
+	}
+}
+
+func Test_GRPCTransport_SignsWireBytesAcrossServices(t *testing.T) {
+	fd, err := protodesc.NewFile(ordersFile(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd, err := protodesc.NewFile(refundsFile(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &ordersServer{}
+	addr := startOrdersServer(t, fd, rd, srv)
+
+	// Reflection must resolve billing.v1.Refunds, which lives in a different
+	// file from the first service the transport looks up.
+	cfg := config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{
+		Transport: "grpc",
+		GRPC: config.GRPCConfig{
+			Target:            addr,
+			Plaintext:         true,
+			Reflection:        true,
+			Methods:           map[string]string{http.MethodPost: "orders.v1.Orders/Create"},
+			CompensateMethod:  "billing.v1.Refunds/Refund",
+			IDField:           "id",
+			CompensateIDField: "order_id",
+		},
+		Auth: config.AuthConfig{Type: "hmac", HMACSecret: hmacSecret, HMACHeader: "X-Signature"},
+	}}
+	tr, err := NewTransport(cfg, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tr.Execute(context.Background(), StepInput{
+		Method:  http.MethodPost,
+		Payload: RequestPayload{Operation: "step1", Data: map[string]any{"customer": "acme"}},
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := tr.Compensate(context.Background(), "", out.ResourceID); err != nil {
+		t.Fatalf("compensate failed: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.refunded) != 1 || srv.refunded[0] != out.ResourceID {
+		t.Fatalf("unexpected refunds: %+v", srv.refunded)
+	}
+	if len(srv.signatures) != 2 || srv.signatures[0] != "ok" || srv.signatures[1] != "ok" {
+		t.Fatalf("unexpected signatures: %+v", srv.signatures)
+	}
+}
+
+func Test_NewTransport_Unsupported(t *testing.T) {
+	if _, err := NewTransport(config.Config{API1: config.ServiceConfig{Transport: "smtp"}}, "api1"); err == nil {
+		t.Fatal("expected error for unsupported transport")
+	}
+	if _, err := NewTransport(config.Config{API1: config.ServiceConfig{Transport: "grpc", GRPC: config.GRPCConfig{Target: "x:1"}}}, "api1"); err == nil {
+		t.Fatal("expected error without descriptor source")
+	}
+}
@@ -0,0 +1,39 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// startHeartbeat records heartbeats for the running activity until stop is
+// called, at a third of its heartbeat timeout. It keeps slow downstream calls
+// (waiting for headers, streaming a large body, polling) from being killed
+// as heartbeat timeouts. Outside an activity it does nothing.
+func startHeartbeat(ctx context.Context, details ...any) (stop func()) {
+	if !activity.IsActivity(ctx) {
+		return func() {}
+	}
+	timeout := activity.GetInfo(ctx).HeartbeatTimeout
+	if timeout <= 0 {
+		return func() {}
+	}
+	activity.RecordHeartbeat(ctx, details...)
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(timeout / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				activity.RecordHeartbeat(ctx, details...)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,110 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// minPollInterval bounds the configured poll interval, so a zero interval
+// cannot turn polling into a busy loop.
+const minPollInterval = time.Second
+
+// awaitCompletion polls an operation the service accepted asynchronously
+// (202 with a `Location` header or `status_url` body field) until it
+// finishes. Polls answered with 202, or whose body has a pending status,
+// keep waiting; a `failed`/`error` status fails the step. The returned
+// response carries the final body, unwrapped from `result` when present.
+func (c *ExternalClient) awaitCompletion(ctx context.Context, reqURL string, accepted *http.Response) (*http.Response, error) {
+	statusURL, err := statusLocation(reqURL, accepted)
+	if err != nil {
+		return nil, err
+	}
+	interval := max(time.Duration(c.cfg.Service(c.service).Poll.IntervalSeconds)*time.Second, minPollInterval)
+	wait := retryAfter(accepted, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for async operation %s: %w", statusURL, ctx.Err())
+		case <-time.After(wait):
+		}
+		resp, err := c.do(ctx, http.MethodGet, statusURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		wait = retryAfter(resp, interval)
+		if resp.StatusCode == http.StatusAccepted {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return resp, nil
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var out map[string]any
+		_ = json.Unmarshal(b, &out)
+		status, _ := out["status"].(string)
+		switch strings.ToLower(status) {
+		case "pending", "queued", "running", "processing", "in_progress":
+			continue
+		case "failed", "error":
+			return nil, fmt.Errorf("async operation failed: %s", string(b))
+		}
+		if r, ok := out["result"].(map[string]any); ok {
+			b, _ = json.Marshal(r)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(b))
+		return resp, nil
+	}
+}
+
+// statusLocation resolves where to poll an accepted operation. Polls carry
+// the service's credentials, so the location must stay on the origin of the
+// request; any other host is rejected.
+func statusLocation(reqURL string, accepted *http.Response) (string, error) {
+	loc := accepted.Header.Get("Location")
+	if loc == "" {
+		var out struct {
+			StatusURL string `json:"status_url"`
+		}
+		_ = json.NewDecoder(accepted.Body).Decode(&out)
+		loc = out.StatusURL
+	}
+	if loc == "" {
+		return "", fmt.Errorf("202 response has no Location header or status_url")
+	}
+	base, err := url.Parse(reqURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(loc)
+	if err != nil {
+		return "", fmt.Errorf("invalid status location %q: %w", loc, err)
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return "", temporal.NewNonRetryableApplicationError(fmt.Sprintf("status location %q is not on the service origin %s://%s", loc, base.Scheme, base.Host), "InvalidStatusLocation", nil)
+	}
+	return u.String(), nil
+}
+
+// retryAfter honours a Retry-After header given in seconds.
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+	return fallback
+}
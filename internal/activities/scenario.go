@@ -0,0 +1,119 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/activity"
+)
+
+// CompensateOperation is the scenario operation key matching rollbacks.
+const CompensateOperation = "compensate"
+
+// Scenario drives mock mode: behaviors are keyed by service and then by
+// operation ("step1", ..., CompensateOperation, or "*" for any operation).
+// Calls without a matching behavior succeed immediately.
+type Scenario map[string]map[string]Behavior
+
+// Behavior is what a mocked call does before returning.
+type Behavior struct {
+	// LatencyMs delays every attempt.
+	LatencyMs int `json:"latency_ms,omitempty"`
+	// FailTimes fails the first N attempts of each activity; -1 fails every attempt.
+	FailTimes int `json:"fail_times,omitempty"`
+	// Status and Message describe the simulated error (default 500).
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// LoadScenario reads a scenario file. An empty path yields an empty scenario.
+func LoadScenario(path string) (Scenario, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+	var s Scenario
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// mockScenario loads the scenario used by transports in mock mode.
+func mockScenario(cfg config.Config) (Scenario, error) {
+	if !cfg.MockMode {
+		return nil, nil
+	}
+	return LoadScenario(cfg.MockScenarioFile)
+}
+
+func (s Scenario) behavior(service, operation string) (Behavior, bool) {
+	ops := s[service]
+	if b, ok := ops[operation]; ok {
+		return b, true
+	}
+	b, ok := ops["*"]
+	return b, ok
+}
+
+// play applies the behavior for service/operation to the current attempt.
+// Attempts are counted by Temporal, so "fail N times" holds per saga and
+// across workers; outside an activity every call is a first attempt.
+func (s Scenario) play(ctx context.Context, service, operation string) error {
+	b, ok := s.behavior(service, operation)
+	if !ok {
+		return nil
+	}
+	if b.LatencyMs > 0 {
+		stop := startHeartbeat(ctx, operation)
+		t := time.NewTimer(time.Duration(b.LatencyMs) * time.Millisecond)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			stop()
+			return ctx.Err()
+		}
+		stop()
+	}
+	attempt := 1
+	if activity.IsActivity(ctx) {
+		attempt = int(activity.GetInfo(ctx).Attempt)
+	}
+	if b.FailTimes < 0 || attempt <= b.FailTimes {
+		status := b.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		msg := b.Message
+		if msg == "" {
+			msg = fmt.Sprintf("scenario: %s %s attempt %d", service, operation, attempt)
+		}
+		return fmt.Errorf("external API error: %d %s", status, msg)
+	}
+	return nil
+}
+
+// mockStep simulates a step according to the scenario.
+func (s Scenario) mockStep(ctx context.Context, service string, in StepInput) (StepResult, error) {
+	if err := s.play(ctx, service, in.Payload.Operation); err != nil {
+		return StepResult{}, err
+	}
+	return mockResult(in), nil
+}
+
+// mockCompensate simulates a rollback according to the scenario.
+func (s Scenario) mockCompensate(ctx context.Context, service string) error {
+	if err := s.play(ctx, service, CompensateOperation); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
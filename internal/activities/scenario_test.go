@@ -0,0 +1,87 @@
+package activities
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Scenario_Play(t *testing.T) {
+	s := Scenario{
+		"api1": {
+			"step1": {FailTimes: 1, Status: 409, Message: "conflict"},
+			"*":     {FailTimes: -1},
+		},
+		"api2": {
+			"step2": {FailTimes: 0},
+		},
+	}
+	for _, tc := range []struct {
+		name      string
+		service   string
+		operation string
+		want      string
+	}{
+		{"exact operation wins over wildcard", "api1", "step1", "external API error: 409 conflict"},
+		{"wildcard covers other operations", "api1", CompensateOperation, "external API error: 500 scenario: api1 compensate attempt 1"},
+		{"fail forever defaults status and message", "api1", "step3", "external API error: 500 scenario: api1 step3 attempt 1"},
+		{"zero fail times succeeds", "api2", "step2", ""},
+		{"no wildcard for service succeeds", "api2", "step1", ""},
+		{"unknown service succeeds", "api3", "step1", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := s.play(context.Background(), tc.service, tc.operation)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.want {
+				t.Fatalf("expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func Test_LoadScenario(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"api1":{"*":{"fail_times":-1,"status":503}}}`, ""},
+		{"malformed", `{"api1":{"*":`, "parse scenario"},
+		{"wrong shape", `{"api1":["step1"]}`, "parse scenario"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_")+".json")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			s, err := LoadScenario(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected %q error, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := s["api1"]["*"]; b.FailTimes != -1 || b.Status != 503 {
+				t.Fatalf("unexpected behavior: %+v", b)
+			}
+		})
+	}
+
+	if s, err := LoadScenario(""); err != nil || s != nil {
+		t.Fatalf("expected an empty path to yield no scenario, got %v %v", s, err)
+	}
+	if _, err := LoadScenario(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "read scenario") {
+		t.Fatalf("expected a read error, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package activities
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// Transport carries a service's step and compensation calls. ExternalClient
+// is the JSON/HTTP implementation; gRPC and GraphQL services use
+// grpcTransport and graphqlTransport.
+type Transport interface {
+	// Execute performs a step.
+	Execute(ctx context.Context, in StepInput) (StepResult, error)
+	// Compensate undoes the resource id created by an earlier step.
+	Compensate(ctx context.Context, baseURL, id string) error
+}
+
+// NewTransport builds the transport configured for the named service.
+func NewTransport(cfg config.Config, service string) (Transport, error) {
+	switch t := cfg.Service(service).Transport; t {
+	case "", "http":
+		return NewExternalClient(cfg, service)
+	case "grpc":
+		return newGRPCTransport(cfg, service)
+	case "graphql":
+		return newGraphQLTransport(cfg, service)
+	default:
+		return nil, fmt.Errorf("%s: unsupported transport: %s", service, t)
+	}
+}
+
+// serviceGuards are the per-service policies every transport applies to its
+// outbound calls: credentials, rate limit and circuit breaker.
+type serviceGuards struct {
+	auth    authenticator
+	breaker *circuitBreaker
+	limiter *rate.Limiter // nil when the service is not rate limited
+}
+
+// newServiceGuards builds the guards configured for service. hc is used for
+// token requests by auth strategies that need them.
+func newServiceGuards(cfg config.Config, service string, hc *http.Client) (serviceGuards, error) {
+	svc := cfg.Service(service)
+	auth, err := newAuthenticator(svc.Auth, hc)
+	if err != nil {
+		return serviceGuards{}, fmt.Errorf("%s: %w", service, err)
+	}
+	var limiter *rate.Limiter
+	if svc.RateLimit.RPS > 0 {
+		limiter = rate.NewLimiter(rate.Limit(svc.RateLimit.RPS), max(svc.RateLimit.Burst, 1))
+	}
+	return serviceGuards{auth: auth, breaker: newCircuitBreaker(service, svc.Breaker), limiter: limiter}, nil
+}
+
+// wait blocks until the rate limit admits another call.
+func (g serviceGuards) wait(ctx context.Context) error {
+	if g.limiter == nil {
+		return nil
+	}
+	if err := g.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait for %s: %w", g.breaker.service, err)
+	}
+	return nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig loads the client certificate and CA bundle named in cfg.
+// Without a CA bundle the system roots are used.
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tc := &tls.Config{ServerName: cfg.ServerName, MinVersion: tls.VersionTLS12}
+	if cfg.MinVersion != "" {
+		v, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS min version: %s", cfg.MinVersion)
+		}
+		tc.MinVersion = v
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	return tc, nil
+}
+
+// newTransport builds a pooled transport for one service. Zero values in cfg
+// fall back to the net/http defaults.
+func newTransport(cfg config.TransportConfig, tc *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tc
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.IdleConnTimeoutSeconds > 0 {
+		t.IdleConnTimeout = time.Duration(cfg.IdleConnTimeoutSeconds) * time.Second
+	}
+	if cfg.KeepAliveSeconds > 0 {
+		d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: time.Duration(cfg.KeepAliveSeconds) * time.Second}
+		t.DialContext = d.DialContext
+	}
+	t.ForceAttemptHTTP2 = cfg.HTTP2
+	if !cfg.HTTP2 {
+		// A non-nil empty map disables the automatic HTTP/2 upgrade.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
@@ -0,0 +1,129 @@
+package activities
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+)
+
+// testCA issues certificates signed by a throwaway CA.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+}
+
+func writeFile(t *testing.T, dir, name string, b []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func Test_MutualTLS_PrivateCA(t *testing.T) {
+	ca := newTestCA(t)
+	srvCert, srvKey := ca.issue(t, "internal.svc", x509.ExtKeyUsageServerAuth)
+	cliCert, cliKey := ca.issue(t, "saga-worker", x509.ExtKeyUsageClientAuth)
+
+	pair, err := tls.X509KeyPair(srvCert, srvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.pem)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "saga-worker" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{pair}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tlsCfg := config.TLSConfig{
+		CertFile:   writeFile(t, dir, "client.crt", cliCert),
+		KeyFile:    writeFile(t, dir, "client.key", cliKey),
+		CAFile:     writeFile(t, dir, "ca.pem", ca.pem),
+		ServerName: "internal.svc",
+		MinVersion: "1.3",
+	}
+	c, err := NewExternalClient(config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{TLS: tlsCfg}}, "api1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.rollback(context.Background(), srv.URL, "r1"); err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+
+	// Without the client certificate the handshake must fail.
+	tlsCfg.CertFile, tlsCfg.KeyFile = "", ""
+	c, _ = NewExternalClient(config.Config{HTTPTimeoutSeconds: 2, API1: config.ServiceConfig{TLS: tlsCfg}}, "api1")
+	if err := c.rollback(context.Background(), srv.URL, "r1"); err == nil {
+		t.Fatal("expected handshake failure without client certificate")
+	}
+}
+
+func Test_TLSConfig_InvalidMinVersion(t *testing.T) {
+	if _, err := newTLSConfig(config.TLSConfig{MinVersion: "2.0"}); err == nil {
+		t.Fatal("expected error for unsupported min version")
+	}
+}
@@ -0,0 +1,103 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Headers of completion webhook deliveries. The signature is computed like
+// outbound "hmac" auth: HMAC-SHA256 over "<method>\n<path?query>\n<timestamp>\n<body>".
+const (
+	WebhookSignatureHeader  = "X-Saga-Signature"
+	WebhookWorkflowIDHeader = "X-Saga-Workflow-Id"
+)
+
+// WebhookInput is a completion notice to deliver. Body is the JSON saga
+// outcome, marshalled by the workflow. SecretName refers to one of the
+// worker's WEBHOOK_SECRETS, so the secret itself stays out of history.
+type WebhookInput struct {
+	URL        string `json:"url"`
+	SecretName string `json:"secret_name,omitempty"`
+	WorkflowID string `json:"workflow_id"`
+	Body       []byte `json:"body"`
+}
+
+// WebhookHostAllowed reports whether rawURL is an absolute http(s) URL whose
+// host is listed in allowed. Entries match a hostname exactly, or any
+// subdomain when written as "*.example.com".
+func WebhookHostAllowed(allowed []string, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if host == a || (strings.HasPrefix(a, "*.") && strings.HasSuffix(host, a[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyCompletion posts the saga outcome to the caller's callback URL,
+// signed with the named secret or, failing that, WEBHOOK_SECRET. URLs and
+// redirects outside WEBHOOK_ALLOWED_HOSTS are refused, as are client errors
+// other than 408 and 429, without retrying.
+func (a *Activities) NotifyCompletion(ctx context.Context, in WebhookInput) error {
+	if !WebhookHostAllowed(a.Cfg.WebhookAllowedHosts, in.URL) {
+		return temporal.NewNonRetryableApplicationError("callback url host is not allowed", "WebhookError", nil)
+	}
+	secret := a.Cfg.WebhookSecret
+	if in.SecretName != "" {
+		var ok bool
+		if secret, ok = a.Cfg.WebhookSecrets[in.SecretName]; !ok {
+			return temporal.NewNonRetryableApplicationError("unknown webhook secret "+in.SecretName, "WebhookError", nil)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.URL, bytes.NewReader(in.Body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid callback url", "WebhookError", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookWorkflowIDHeader, in.WorkflowID)
+	if secret != "" {
+		_ = hmacAuth{header: WebhookSignatureHeader, secret: []byte(secret)}.authenticate(req, in.Body)
+	}
+
+	hc := &http.Client{
+		Timeout: a.Cfg.HTTPTimeout(),
+		CheckRedirect: func(r *http.Request, _ []*http.Request) error {
+			if !WebhookHostAllowed(a.Cfg.WebhookAllowedHosts, r.URL.String()) {
+				return temporal.NewNonRetryableApplicationError("callback redirect host is not allowed", "WebhookError", nil)
+			}
+			return nil
+		},
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
+		return fmt.Errorf("deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("webhook rejected: %d %s", resp.StatusCode, string(b))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return temporal.NewNonRetryableApplicationError(err.Error(), "WebhookError", err)
+	}
+	return err
+}
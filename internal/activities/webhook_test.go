@@ -0,0 +1,107 @@
+package activities
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/temporal"
+)
+
+func Test_NotifyCompletion_SignsBody(t *testing.T) {
+	body := []byte(`{"status":"completed"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("whsec"))
+		fmt.Fprintf(mac, "%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), r.Header.Get(WebhookSignatureHeader+"-Timestamp"))
+		mac.Write(got)
+		if !hmac.Equal([]byte(r.Header.Get(WebhookSignatureHeader)), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+			t.Errorf("bad signature %q", r.Header.Get(WebhookSignatureHeader))
+		}
+		if r.Header.Get(WebhookWorkflowIDHeader) != "saga-1" || string(got) != string(body) {
+			t.Errorf("unexpected delivery %v %s", r.Header, got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	a := &Activities{Cfg: config.Config{
+		HTTPTimeoutSeconds:  2,
+		WebhookAllowedHosts: []string{"127.0.0.1"},
+		WebhookSecret:       "default-secret",
+		WebhookSecrets:      map[string]string{"billing": "whsec"},
+	}}
+	if err := a.NotifyCompletion(context.Background(), WebhookInput{URL: srv.URL + "/hooks?x=1", SecretName: "billing", WorkflowID: "saga-1", Body: body}); err != nil {
+		t.Fatalf("delivery failed: %v", err)
+	}
+	err := a.NotifyCompletion(context.Background(), WebhookInput{URL: srv.URL, SecretName: "ops", Body: body})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Fatalf("expected unknown secret name to fail permanently, got %v", err)
+	}
+}
+
+func Test_NotifyCompletion_AllowedHostsOnly(t *testing.T) {
+	var hits int
+	inside := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer inside.Close()
+	// localhost is not allowed, so following this redirect is refused
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(inside.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	a := &Activities{Cfg: config.Config{HTTPTimeoutSeconds: 2, WebhookAllowedHosts: []string{"127.0.0.1"}}}
+	for _, u := range []string{"http://169.254.169.254/latest/meta-data", "file:///etc/passwd", redirect.URL} {
+		err := a.NotifyCompletion(context.Background(), WebhookInput{URL: u, Body: []byte(`{}`)})
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+			t.Fatalf("%s: expected a permanent refusal, got %v", u, err)
+		}
+	}
+	if hits != 0 {
+		t.Fatalf("expected no delivery outside the allowed hosts, got %d", hits)
+	}
+
+	for host, want := range map[string]bool{
+		"https://hooks.example.com/x":   true,
+		"https://a.hooks.example.com/x": true,
+		"https://example.com/x":         false,
+		"https://evilhooks.example.com": false,
+	} {
+		if got := WebhookHostAllowed([]string{"*.hooks.example.com", "hooks.example.com"}, host); got != want {
+			t.Fatalf("%s: allowed=%v, want %v", host, got, want)
+		}
+	}
+}
+
+func Test_NotifyCompletion_Retryability(t *testing.T) {
+	for status, retryable := range map[int]bool{
+		http.StatusBadRequest:         false,
+		http.StatusGone:               false,
+		http.StatusTooManyRequests:    true,
+		http.StatusServiceUnavailable: true,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) }))
+		a := &Activities{Cfg: config.Config{HTTPTimeoutSeconds: 2, WebhookAllowedHosts: []string{"127.0.0.1"}}}
+		err := a.NotifyCompletion(context.Background(), WebhookInput{URL: srv.URL, Body: []byte(`{}`)})
+		srv.Close()
+		var appErr *temporal.ApplicationError
+		nonRetryable := errors.As(err, &appErr) && appErr.NonRetryable()
+		if err == nil || nonRetryable == retryable {
+			t.Fatalf("status %d: expected retryable=%v, got %v", status, retryable, err)
+		}
+	}
+}
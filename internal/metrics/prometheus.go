@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/uber-go/tally/v4"
+	"github.com/uber-go/tally/v4/prometheus"
+	"go.temporal.io/sdk/client"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+)
+
+// latencyBuckets are the histogram buckets of timers, in seconds, spanning
+// fast HTTP calls to steps that poll or wait for a callback.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// reportInterval is how often the scope flushes to the Prometheus registry.
+const reportInterval = time.Second
+
+// Handler is a client.MetricsHandler that reports the SDK's own metrics and
+// the ones emitted by activities to a Prometheus registry through tally, and
+// serves that registry. Timers are histograms over latencyBuckets.
+type Handler struct {
+	client.MetricsHandler
+	http  http.Handler
+	scope io.Closer
+}
+
+func NewHandler() *Handler {
+	reg := prom.NewRegistry()
+	reporter := prometheus.NewReporter(prometheus.Options{
+		Registerer:              reg,
+		Gatherer:                reg,
+		DefaultTimerType:        prometheus.HistogramTimerType,
+		DefaultHistogramBuckets: latencyBuckets,
+		OnRegisterError:         func(err error) { log.Printf("metrics: %v", err) },
+	})
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		CachedReporter:  reporter,
+		Separator:       prometheus.DefaultSeparator,
+		SanitizeOptions: &sdktally.PrometheusSanitizeOptions,
+	}, reportInterval)
+	return &Handler{
+		MetricsHandler: sdktally.NewMetricsHandler(sdktally.NewPrometheusNamingScope(scope)),
+		http:           reporter.HTTPHandler(),
+		scope:          closer,
+	}
+}
+
+// ServeHTTP writes the registry in the Prometheus exposition format.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.http.ServeHTTP(w, r)
+}
+
+// Close flushes pending values and stops reporting.
+func (h *Handler) Close() error {
+	return h.scope.Close()
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Handler_ServesPrometheusText(t *testing.T) {
+	h := NewHandler()
+	svc := h.WithTags(map[string]string{"service": "api1"})
+	svc.Gauge("saga_circuit_breaker_state").Update(1)
+	svc.Counter("saga_circuit_breaker_rejected").Inc(2)
+	svc.Counter("saga_circuit_breaker_rejected").Inc(1)
+	h.Timer("temporal_request_latency").Record(1500 * time.Millisecond)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"saga_circuit_breaker_state{service=\"api1\"} 1\n",
+		"saga_circuit_breaker_rejected_total{service=\"api1\"} 3\n",
+		"# TYPE temporal_request_latency_seconds histogram\n",
+		"temporal_request_latency_seconds_bucket{le=\"1\"} 0\n",
+		"temporal_request_latency_seconds_bucket{le=\"2.5\"} 1\n",
+		"temporal_request_latency_seconds_count 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package saga
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -26,7 +27,12 @@ func (s *Saga) Add(c Rollback) {
 }
 
 // Fail triggers rollbacks in reverse order and returns a combined error.
+// Rollbacks run on a disconnected context when the workflow was cancelled,
+// so a cancelled saga is still compensated.
 func (s *Saga) Fail(ctx workflow.Context, cause error) error {
+	if ctx.Err() != nil {
+		ctx, _ = workflow.NewDisconnectedContext(ctx)
+	}
 	var firstErr error = cause
 	// execute in reverse order
 	for i := len(s.rollbacks) - 1; i >= 0; i-- {
@@ -57,6 +63,32 @@ func ExecuteActivity[T any](ctx workflow.Context, s *Saga, act any, rollback Rol
 	return result, nil
 }
 
+// ExecuteActivityWithPartial is ExecuteActivity for activities that can fail
+// after changing something, such as a create whose response is rejected. When
+// the activity fails with an application error of partialType carrying a T as
+// details, partial receives it and the rollback is registered before
+// compensating, so that change is undone too. Details of other error types
+// are ignored: they may come from outside, like a partner's callback.
+func ExecuteActivityWithPartial[T any](ctx workflow.Context, s *Saga, act any, rollback Rollback, partialType string, partial func(T), args ...any) (T, error) {
+	var zero T
+	f := workflow.ExecuteActivity(ctx, act, args...)
+	var result T
+	if err := f.Get(ctx, &result); err != nil {
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) && appErr.Type() == partialType && appErr.HasDetails() && appErr.Details(&result) == nil {
+			partial(result)
+			if rollback != nil {
+				s.Add(rollback)
+			}
+		}
+		return zero, s.Fail(ctx, err)
+	}
+	if rollback != nil {
+		s.Add(rollback)
+	}
+	return result, nil
+}
+
 // WithDeadline sets a workflow context with a deadline from now.
 func WithDeadline(ctx workflow.Context, deadline time.Time) workflow.Context {
 	return workflow.WithActivityOptions(ctx, workflow.GetActivityOptions(ctx))
@@ -69,5 +101,3 @@ func WithActivityOptions(ctx workflow.Context, ao workflow.ActivityOptions) work
 
 // Background just mirrors context.Background for symmetry in call sites outside workflow.
 func Background() context.Context { return context.Background() }
-
-
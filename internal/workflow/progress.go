@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// ProgressQuery is the query type returning a saga's SagaProgress.
+const ProgressQuery = "saga_progress"
+
+// Saga and step statuses reported by ProgressQuery.
+const (
+	StatusPending            = "pending"
+	StatusRunning            = "running"
+	StatusCompleted          = "completed"
+	StatusFailed             = "failed"
+	StatusCompensating       = "compensating"
+	StatusCompensated        = "compensated"
+	StatusCompensationFailed = "compensation_failed"
+)
+
+// StepProgress is the state of one saga step.
+type StepProgress struct {
+	Name       string         `json:"name"`
+	Service    string         `json:"service"`
+	Status     string         `json:"status"`
+	ResourceID string         `json:"resource_id,omitempty"`
+	Result     map[string]any `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	// CompensationError is set when the step's rollback failed.
+	CompensationError string `json:"compensation_error,omitempty"`
+}
+
+// SagaProgress is the state of a saga. Status is running or compensating
+// while the workflow is open; a failed saga ends compensated when every
+// completed step was rolled back, failed otherwise.
+type SagaProgress struct {
+	Method       string         `json:"method"`
+	Status       string         `json:"status"`
+	FailureCause string         `json:"failure_cause,omitempty"`
+	Steps        []StepProgress `json:"steps"`
+}
+
+// progressTracker records SagaProgress as the workflow advances.
+type progressTracker struct {
+	p SagaProgress
+}
+
+func newProgressTracker(method string) *progressTracker {
+	t := &progressTracker{p: SagaProgress{Method: method, Status: StatusRunning}}
+	for i, svc := range []string{"api1", "api2", "api3"} {
+		t.p.Steps = append(t.p.Steps, StepProgress{Name: fmt.Sprintf("step%d", i+1), Service: svc, Status: StatusPending})
+	}
+	return t
+}
+
+// register exposes the progress through ProgressQuery.
+func (t *progressTracker) register(ctx workflow.Context) error {
+	return workflow.SetQueryHandler(ctx, ProgressQuery, func() (SagaProgress, error) {
+		p := t.p
+		p.Steps = append([]StepProgress(nil), t.p.Steps...)
+		return p, nil
+	})
+}
+
+func (t *progressTracker) step(name string) *StepProgress {
+	for i := range t.p.Steps {
+		if t.p.Steps[i].Name == name {
+			return &t.p.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (t *progressTracker) start(name string) {
+	t.step(name).Status = StatusRunning
+}
+
+func (t *progressTracker) complete(name, id string, body map[string]any) {
+	s := t.step(name)
+	s.Status, s.ResourceID, s.Result = StatusCompleted, id, body
+}
+
+// fail marks the running step as failed. Compensation may already have run,
+// so it only records the cause when the step has not been marked yet.
+func (t *progressTracker) fail(name string, err error) {
+	s := t.step(name)
+	if s.Status == StatusRunning {
+		s.Status = StatusFailed
+	}
+	if s.Error == "" {
+		s.Error = rootCause(err).Error()
+	}
+}
+
+// compensate runs rollback for a completed step, recording its outcome.
+func (t *progressTracker) compensate(name string, rollback func() error) error {
+	t.p.Status = StatusCompensating
+	for i := range t.p.Steps {
+		if t.p.Steps[i].Status == StatusRunning {
+			t.p.Steps[i].Status = StatusFailed
+		}
+	}
+	s := t.step(name)
+	s.Status = StatusCompensating
+	err := rollback()
+	if err != nil {
+		s.Status, s.CompensationError = StatusCompensationFailed, rootCause(err).Error()
+	} else {
+		s.Status = StatusCompensated
+	}
+	return err
+}
+
+// finish sets the final saga status from the workflow's outcome.
+func (t *progressTracker) finish(err error) {
+	if err == nil {
+		t.p.Status = StatusCompleted
+		return
+	}
+	t.p.FailureCause = rootCause(err).Error()
+	t.p.Status = StatusCompensated
+	for _, s := range t.p.Steps {
+		if s.Status == StatusCompleted || s.Status == StatusCompensationFailed {
+			t.p.Status = StatusFailed
+		}
+	}
+}
+
+// rootCause unwraps the saga and activity error wrappers down to the
+// error the step actually reported.
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
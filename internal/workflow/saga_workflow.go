@@ -13,27 +13,58 @@ import (
 type OperationInput struct {
 	Method string         `json:"method"`
 	Data   map[string]any `json:"data"`
+	// Optional per-step data merged over Data, keyed by step name ("step1"...).
+	// String values may reference earlier step outputs, e.g. "{{ steps.step1.id }}".
+	StepData map[string]map[string]any `json:"step_data,omitempty"`
+	// Optional per-step HTTP method overriding Method, keyed by step name,
+	// e.g. {"step1": "GET"} to read a resource that later steps reference.
+	StepMethods map[string]string `json:"step_methods,omitempty"`
 	// Optional resource IDs for PUT/DELETE
 	ID1 string `json:"id1,omitempty"`
 	ID2 string `json:"id2,omitempty"`
 	ID3 string `json:"id3,omitempty"`
+	// Optional completion webhook, signed with the worker's WEBHOOK_SECRETS entry named
+	// CallbackSecretName (or its WEBHOOK_SECRET). Only the name is kept in history.
+	CallbackURL        string `json:"callback_url,omitempty"`
+	CallbackSecretName string `json:"callback_secret_name,omitempty"`
 }
 
+// rollbackServiceChange versions the switch from Rollback to RollbackService.
+const rollbackServiceChange = "rollback-service"
+
 type OperationResult struct {
 	Step1ID string `json:"step1_id"`
 	Step2ID string `json:"step2_id"`
 	Step3ID string `json:"step3_id"`
 }
 
-func SagaWorkflow(ctx workflow.Context, cfg configpkg.Config, in OperationInput) (OperationResult, error) {
-	var result OperationResult
+func SagaWorkflow(ctx workflow.Context, cfg configpkg.Config, in OperationInput) (result OperationResult, err error) {
 	s := saga.New()
 
+	// Step and compensation outcomes, exposed through ProgressQuery
+	progress := newProgressTracker(in.Method)
+	if err := progress.register(ctx); err != nil {
+		return result, err
+	}
+	defer func() {
+		progress.finish(err)
+		if cfg.SearchAttributes {
+			if uerr := workflow.UpsertTypedSearchAttributes(ctx, StatusAttribute.ValueSet(progress.p.Status)); uerr != nil {
+				workflow.GetLogger(ctx).Error("failed to record saga status", "error", uerr)
+			}
+		}
+		if in.CallbackURL != "" {
+			notifyCompletion(ctx, cfg, in, result, progress.p, err)
+		}
+	}()
+
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout:    cfg.HTTPTimeout(),
 		ScheduleToCloseTimeout: cfg.TransactionTimeout(),
 		ScheduleToStartTimeout: cfg.HTTPTimeout(),
-		HeartbeatTimeout:      cfg.HTTPTimeout() / 2,
+		HeartbeatTimeout:       cfg.HTTPTimeout() / 2,
+		// On cancellation, wait for the in-flight step to stop so its outcome is known before compensating
+		WaitForCancellation: true,
 		RetryPolicy: &temporal.RetryPolicy{
 			InitialInterval:    time.Second,
 			BackoffCoefficient: 2,
@@ -42,66 +73,139 @@ func SagaWorkflow(ctx workflow.Context, cfg configpkg.Config, in OperationInput)
 	}
 	ctx = saga.WithActivityOptions(ctx, ao)
 
+	// Steps may poll async operations, so they get the service's poll budget on top of the HTTP timeout.
+	// Callback steps wait once for the partner's webhook: no heartbeats, and a timeout compensates.
+	stepCtx := func(svc configpkg.ServiceConfig) workflow.Context {
+		o := ao
+		o.StartToCloseTimeout = cfg.StepTimeout(svc)
+		if svc.Callback.Enabled {
+			o.HeartbeatTimeout = 0
+			o.ScheduleToCloseTimeout = o.StartToCloseTimeout
+			o.WaitForCancellation = false // pending callbacks cannot observe cancellation
+		}
+		return saga.WithActivityOptions(ctx, o)
+	}
+
 	acts := &activities.Activities{Cfg: cfg}
 
-	// Helper to decide if we should add compensation (only for POST create)
-	shouldRollback := in.Method == "POST"
+	// Method of a step: its StepMethods entry, else the saga's Method
+	methodOf := func(step string) string {
+		if m := in.StepMethods[step]; m != "" {
+			return m
+		}
+		return in.Method
+	}
+	// Only steps that created a resource (POST) are compensated
+	shouldRollback := func(step string) bool { return methodOf(step) == "POST" }
+
+	// Rollbacks name their service since version 1; older histories scheduled
+	// the (baseURL, id) Rollback activity and must replay with it.
+	rollback := func(c workflow.Context, service, baseURL, id string) error {
+		if workflow.GetVersion(c, rollbackServiceChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+			return workflow.ExecuteActivity(c, acts.Rollback, baseURL, id).Get(c, nil)
+		}
+		return workflow.ExecuteActivity(c, acts.RollbackService, service, baseURL, id).Get(c, nil)
+	}
+
+	// Outputs of completed steps, referenced by `{{ steps.<name>.<field> }}` in step payloads
+	outputs := stepOutputs{}
+	render := func(step string) (map[string]any, error) {
+		data, err := renderData(mergeData(in.Data, in.StepData[step]), outputs)
+		if err != nil {
+			return nil, s.Fail(ctx, temporal.NewNonRetryableApplicationError(err.Error(), "TemplateError", err))
+		}
+		return data, nil
+	}
 
 	// Step 1
 	rollback1 := func(c workflow.Context) error {
-		if !shouldRollback || result.Step1ID == "" {
+		if !shouldRollback("step1") || result.Step1ID == "" {
 			return nil
 		}
-		return workflow.ExecuteActivity(c, acts.Rollback, cfg.API1BaseURL, result.Step1ID).Get(c, nil)
+		return progress.compensate("step1", func() error {
+			return rollback(c, "api1", cfg.API1BaseURL, result.Step1ID)
+		})
 	}
-	res1, err := saga.ExecuteActivity[activities.StepResult](ctx, s, acts.Step1, rollback1, activities.StepInput{
+	progress.start("step1")
+	data1, err := render("step1")
+	if err != nil {
+		progress.fail("step1", err)
+		return result, err
+	}
+	res1, err := saga.ExecuteActivityWithPartial(stepCtx(cfg.API1), s, acts.Step1, rollback1, activities.ContractViolationErrorType, func(r activities.StepResult) { result.Step1ID = r.ResourceID }, activities.StepInput{
 		BaseURL:    cfg.API1BaseURL,
-		Method:     in.Method,
+		Method:     methodOf("step1"),
 		ResourceID: in.ID1,
-		Payload:    activities.RequestPayload{Operation: "step1", Data: in.Data},
+		Payload:    activities.RequestPayload{Operation: "step1", Data: data1},
+		Callback:   cfg.API1.Callback.Enabled,
 	})
 	if err != nil {
+		progress.fail("step1", err)
 		return result, err
 	}
 	result.Step1ID = res1.ResourceID
+	progress.complete("step1", res1.ResourceID, res1.Body)
+	outputs["step1"] = res1
 
 	// Step 2
 	comp2 := func(c workflow.Context) error {
-		if !shouldRollback || result.Step2ID == "" {
+		if !shouldRollback("step2") || result.Step2ID == "" {
 			return nil
 		}
-		return workflow.ExecuteActivity(c, acts.Rollback, cfg.API2BaseURL, result.Step2ID).Get(c, nil)
+		return progress.compensate("step2", func() error {
+			return rollback(c, "api2", cfg.API2BaseURL, result.Step2ID)
+		})
+	}
+	progress.start("step2")
+	data2, err := render("step2")
+	if err != nil {
+		progress.fail("step2", err)
+		return result, err
 	}
-	res2, err := saga.ExecuteActivity[activities.StepResult](ctx, s, acts.Step2, comp2, activities.StepInput{
+	res2, err := saga.ExecuteActivityWithPartial(stepCtx(cfg.API2), s, acts.Step2, comp2, activities.ContractViolationErrorType, func(r activities.StepResult) { result.Step2ID = r.ResourceID }, activities.StepInput{
 		BaseURL:    cfg.API2BaseURL,
-		Method:     in.Method,
+		Method:     methodOf("step2"),
 		ResourceID: in.ID2,
-		Payload:    activities.RequestPayload{Operation: "step2", Data: in.Data},
+		Payload:    activities.RequestPayload{Operation: "step2", Data: data2},
+		Callback:   cfg.API2.Callback.Enabled,
 	})
 	if err != nil {
+		progress.fail("step2", err)
 		return result, err
 	}
 	result.Step2ID = res2.ResourceID
+	progress.complete("step2", res2.ResourceID, res2.Body)
+	outputs["step2"] = res2
 
 	// Step 3
 	comp3 := func(c workflow.Context) error {
-		if !shouldRollback || result.Step3ID == "" {
+		if !shouldRollback("step3") || result.Step3ID == "" {
 			return nil
 		}
-		return workflow.ExecuteActivity(c, acts.Rollback, cfg.API3BaseURL, result.Step3ID).Get(c, nil)
+		return progress.compensate("step3", func() error {
+			return rollback(c, "api3", cfg.API3BaseURL, result.Step3ID)
+		})
 	}
-	res3, err := saga.ExecuteActivity[activities.StepResult](ctx, s, acts.Step3, comp3, activities.StepInput{
+	progress.start("step3")
+	data3, err := render("step3")
+	if err != nil {
+		progress.fail("step3", err)
+		return result, err
+	}
+	res3, err := saga.ExecuteActivityWithPartial(stepCtx(cfg.API3), s, acts.Step3, comp3, activities.ContractViolationErrorType, func(r activities.StepResult) { result.Step3ID = r.ResourceID }, activities.StepInput{
 		BaseURL:    cfg.API3BaseURL,
-		Method:     in.Method,
+		Method:     methodOf("step3"),
 		ResourceID: in.ID3,
-		Payload:    activities.RequestPayload{Operation: "step3", Data: in.Data},
+		Payload:    activities.RequestPayload{Operation: "step3", Data: data3},
+		Callback:   cfg.API3.Callback.Enabled,
 	})
 	if err != nil {
+		progress.fail("step3", err)
 		return result, err
 	}
 	result.Step3ID = res3.ResourceID
+	progress.complete("step3", res3.ResourceID, res3.Body)
+	outputs["step3"] = res3
 
 	return result, nil
 }
-
-
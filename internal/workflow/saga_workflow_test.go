@@ -1,216 +1,878 @@
 package workflow
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
 	configpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
 )
 
 type mockStore struct {
-    mu            sync.Mutex
-    deletions     []string
-    nextIDCounter int
+	mu            sync.Mutex
+	deletions     []string
+	nextIDCounter int
 }
 
 func (m *mockStore) recordDelete(tag string) {
-    m.mu.Lock()
-    defer m.mu.Unlock()
-    m.deletions = append(m.deletions, tag)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deletions = append(m.deletions, tag)
 }
 
 func setupServer(t *testing.T, behavior map[string]func(http.ResponseWriter, *http.Request)) *httptest.Server {
-    t.Helper()
-    mux := http.NewServeMux()
-    for path, h := range behavior {
-        mux.HandleFunc(path, h)
-    }
-    return httptest.NewServer(mux)
+	t.Helper()
+	mux := http.NewServeMux()
+	for path, h := range behavior {
+		mux.HandleFunc(path, h)
+	}
+	return httptest.NewServer(mux)
 }
 
 func defaultHandlers(t *testing.T, store *mockStore, fail map[string]bool, sleep map[string]time.Duration) map[string]func(http.ResponseWriter, *http.Request) {
-    return map[string]func(http.ResponseWriter, *http.Request){
-        "/api1/create": func(w http.ResponseWriter, r *http.Request) {
-            if d := sleep["api1"]; d > 0 {
-                time.Sleep(d)
-            }
-            if fail["api1"] {
-                http.Error(w, "fail1", http.StatusInternalServerError)
-                return
-            }
-            _ = json.NewDecoder(r.Body).Decode(&struct{}{})
-            _ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "a1"})
-        },
-        "/api2/create": func(w http.ResponseWriter, r *http.Request) {
-            if d := sleep["api2"]; d > 0 {
-                time.Sleep(d)
-            }
-            if fail["api2"] {
-                http.Error(w, "fail2", http.StatusInternalServerError)
-                return
-            }
-            _ = json.NewDecoder(r.Body).Decode(&struct{}{})
-            _ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "b2"})
-        },
-        "/api3/create": func(w http.ResponseWriter, r *http.Request) {
-            if d := sleep["api3"]; d > 0 {
-                time.Sleep(d)
-            }
-            if fail["api3"] {
-                http.Error(w, "fail3", http.StatusInternalServerError)
-                return
-            }
-            _ = json.NewDecoder(r.Body).Decode(&struct{}{})
-            _ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "c3"})
-        },
-        "/api1/a1": func(w http.ResponseWriter, r *http.Request) {
-            if r.Method == http.MethodDelete {
-                store.recordDelete("api1:a1")
-                w.WriteHeader(200)
-                _, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
-                return
-            }
-            w.WriteHeader(405)
-        },
-        "/api2/b2": func(w http.ResponseWriter, r *http.Request) {
-            if r.Method == http.MethodDelete {
-                store.recordDelete("api2:b2")
-                w.WriteHeader(200)
-                _, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
-                return
-            }
-            w.WriteHeader(405)
-        },
-        "/api3/c3": func(w http.ResponseWriter, r *http.Request) {
-            if r.Method == http.MethodDelete {
-                store.recordDelete("api3:c3")
-                w.WriteHeader(200)
-                _, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
-                return
-            }
-            w.WriteHeader(405)
-        },
-    }
+	return map[string]func(http.ResponseWriter, *http.Request){
+		"/api1/create": func(w http.ResponseWriter, r *http.Request) {
+			if d := sleep["api1"]; d > 0 {
+				time.Sleep(d)
+			}
+			if fail["api1"] {
+				http.Error(w, "fail1", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&struct{}{})
+			_ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "a1"})
+		},
+		"/api2/create": func(w http.ResponseWriter, r *http.Request) {
+			if d := sleep["api2"]; d > 0 {
+				time.Sleep(d)
+			}
+			if fail["api2"] {
+				http.Error(w, "fail2", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&struct{}{})
+			_ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "b2"})
+		},
+		"/api3/create": func(w http.ResponseWriter, r *http.Request) {
+			if d := sleep["api3"]; d > 0 {
+				time.Sleep(d)
+			}
+			if fail["api3"] {
+				http.Error(w, "fail3", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&struct{}{})
+			_ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "c3"})
+		},
+		"/api1/a1": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				store.recordDelete("api1:a1")
+				w.WriteHeader(200)
+				_, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
+				return
+			}
+			w.WriteHeader(405)
+		},
+		"/api2/b2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				store.recordDelete("api2:b2")
+				w.WriteHeader(200)
+				_, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
+				return
+			}
+			w.WriteHeader(405)
+		},
+		"/api3/c3": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				store.recordDelete("api3:c3")
+				w.WriteHeader(200)
+				_, _ = w.Write([]byte("{\"status\":\"deleted\"}"))
+				return
+			}
+			w.WriteHeader(405)
+		},
+	}
 }
 
 func newCfg(base string) configpkg.Config {
-    return configpkg.Config{
-        TemporalAddress:            "",
-        TemporalNamespace:          "default",
-        TemporalTaskQueue:          "saga-task-queue-test",
-        TransactionTimeoutSeconds:  10,
-        API1BaseURL:                base + "/api1",
-        API2BaseURL:                base + "/api2",
-        API3BaseURL:                base + "/api3",
-        MockMode:                   false,
-        HTTPTimeoutSeconds:         2,
-        ServerPort:                 "0",
-    }
+	return configpkg.Config{
+		TemporalAddress:           "",
+		TemporalNamespace:         "default",
+		TemporalTaskQueue:         "saga-task-queue-test",
+		TransactionTimeoutSeconds: 10,
+		API1BaseURL:               base + "/api1",
+		API2BaseURL:               base + "/api2",
+		API3BaseURL:               base + "/api3",
+		MockMode:                  false,
+		HTTPTimeoutSeconds:        2,
+		ServerPort:                "0",
+	}
 }
 
 func registerActivities(env *testsuite.TestWorkflowEnvironment, cfg configpkg.Config) {
-    acts := &activities.Activities{Cfg: cfg}
-    env.RegisterActivity(acts.Step1)
-    env.RegisterActivity(acts.Step2)
-    env.RegisterActivity(acts.Step3)
-    env.RegisterActivity(acts.Rollback)
+	acts := &activities.Activities{Cfg: cfg}
+	env.RegisterActivity(acts.Step1)
+	env.RegisterActivity(acts.Step2)
+	env.RegisterActivity(acts.Step3)
+	env.RegisterActivity(acts.Rollback)
+	env.RegisterActivity(acts.RollbackService)
+	env.RegisterActivity(acts.NotifyCompletion)
 }
 
 func Test_Saga_Success(t *testing.T) {
-    var suite testsuite.WorkflowTestSuite
-    env := suite.NewTestWorkflowEnvironment()
-    env.SetTestTimeout(10 * time.Second)
-    store := &mockStore{}
-    srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{}))
-    defer srv.Close()
-
-    cfg := newCfg(srv.URL)
-    env.RegisterWorkflow(SagaWorkflow)
-    registerActivities(env, cfg)
-
-    env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
-    if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
-        t.Fatalf("workflow failed: %v", env.GetWorkflowError())
-    }
-    var out OperationResult
-    _ = env.GetWorkflowResult(&out)
-    if out.Step1ID != "a1" || out.Step2ID != "b2" || out.Step3ID != "c3" {
-        t.Fatalf("unexpected output: %+v", out)
-    }
-    if len(store.deletions) != 0 {
-        t.Fatalf("unexpected compensations: %+v", store.deletions)
-    }
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("workflow failed: %v", env.GetWorkflowError())
+	}
+	var out OperationResult
+	_ = env.GetWorkflowResult(&out)
+	if out.Step1ID != "a1" || out.Step2ID != "b2" || out.Step3ID != "c3" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if len(store.deletions) != 0 {
+		t.Fatalf("unexpected compensations: %+v", store.deletions)
+	}
 }
 
 func Test_Saga_Fail_Step2_Rollback1(t *testing.T) {
-    var suite testsuite.WorkflowTestSuite
-    env := suite.NewTestWorkflowEnvironment()
-    env.SetTestTimeout(10 * time.Second)
-    store := &mockStore{}
-    srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api2": true}, map[string]time.Duration{}))
-    defer srv.Close()
-
-    cfg := newCfg(srv.URL)
-    env.RegisterWorkflow(SagaWorkflow)
-    registerActivities(env, cfg)
-
-    env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
-    if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
-        t.Fatalf("expected workflow error but got nil")
-    }
-    if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
-        t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
-    }
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api2": true}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
+		t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
+	}
+}
+
+func Test_Saga_Rollback_DefaultVersionUsesLegacyActivity(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api3": true}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	env.OnGetVersion(rollbackServiceChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	var legacy []string
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		if info.ActivityType.Name == "Rollback" {
+			legacy = append(legacy, info.ActivityType.Name)
+		}
+	})
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if len(legacy) != 2 {
+		t.Fatalf("expected both rollbacks through the legacy activity, got %v", legacy)
+	}
+	if len(store.deletions) != 2 || store.deletions[0] != "api2:b2" || store.deletions[1] != "api1:a1" {
+		t.Fatalf("expected rollback of step2 then step1, got %+v", store.deletions)
+	}
 }
 
 func Test_Saga_Fail_Step3_Rollback2Then1(t *testing.T) {
-    var suite testsuite.WorkflowTestSuite
-    env := suite.NewTestWorkflowEnvironment()
-    env.SetTestTimeout(10 * time.Second)
-    store := &mockStore{}
-    srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api3": true}, map[string]time.Duration{}))
-    defer srv.Close()
-
-    cfg := newCfg(srv.URL)
-    env.RegisterWorkflow(SagaWorkflow)
-    registerActivities(env, cfg)
-
-    env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
-    if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
-        t.Fatalf("expected workflow error but got nil")
-    }
-    if len(store.deletions) != 2 || store.deletions[0] != "api2:b2" || store.deletions[1] != "api1:a1" {
-        t.Fatalf("expected rollback order [api2, api1], got %+v", store.deletions)
-    }
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api3": true}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if len(store.deletions) != 2 || store.deletions[0] != "api2:b2" || store.deletions[1] != "api1:a1" {
+		t.Fatalf("expected rollback order [api2, api1], got %+v", store.deletions)
+	}
 }
 
 func Test_Saga_Timeout_Rollback1(t *testing.T) {
-    var suite testsuite.WorkflowTestSuite
-    env := suite.NewTestWorkflowEnvironment()
-    env.SetTestTimeout(10 * time.Second)
-    store := &mockStore{}
-    // Sleep on api2 longer than HTTP timeout to force activity timeout
-    srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{"api2": 3 * time.Second}))
-    defer srv.Close()
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	// Sleep on api2 longer than HTTP timeout to force activity timeout
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{"api2": 3 * time.Second}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	cfg.HTTPTimeoutSeconds = 1
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow timeout/error")
+	}
+	if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
+		t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
+	}
+}
+
+func Test_Saga_StepOutputChaining(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	handlers := defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{})
+	var step2Data map[string]any
+	handlers["/api2/create"] = func(w http.ResponseWriter, r *http.Request) {
+		var p activities.RequestPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		step2Data = p.Data
+		_ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "b2"})
+	}
+	srv := setupServer(t, handlers)
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{
+		Method:   http.MethodPost,
+		Data:     map[string]any{"k": "v"},
+		StepData: map[string]map[string]any{"step2": {"parent": "{{ steps.step1.id }}", "status": "{{ steps.step1.status }}"}},
+	})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("workflow failed: %v", env.GetWorkflowError())
+	}
+	if step2Data["k"] != "v" || step2Data["parent"] != "a1" || step2Data["status"] != "ok" {
+		t.Fatalf("unexpected step2 payload: %+v", step2Data)
+	}
+}
+
+func Test_Saga_StepMethods_GetThenCreate(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	handlers := defaultHandlers(t, store, map[string]bool{"api3": true}, map[string]time.Duration{})
+	var step1Method string
+	handlers["/api1/src"] = func(w http.ResponseWriter, r *http.Request) {
+		step1Method = r.Method
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "src", "sku": "A-1"})
+	}
+	var step2Data map[string]any
+	handlers["/api2/create"] = func(w http.ResponseWriter, r *http.Request) {
+		var p activities.RequestPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		step2Data = p.Data
+		_ = json.NewEncoder(w).Encode(activities.ResponsePayload{Status: "ok", ID: "b2"})
+	}
+	srv := setupServer(t, handlers)
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{
+		Method:      http.MethodPost,
+		StepMethods: map[string]string{"step1": http.MethodGet},
+		ID1:         "src",
+		StepData:    map[string]map[string]any{"step2": {"sku": "{{ steps.step1.sku }}"}},
+	})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if step1Method != http.MethodGet {
+		t.Fatalf("expected step1 to GET, got %q", step1Method)
+	}
+	if step2Data["sku"] != "A-1" {
+		t.Fatalf("expected step2 to consume step1 body, got %+v", step2Data)
+	}
+	// The GET step created nothing, so only step2 is compensated.
+	if len(store.deletions) != 1 || store.deletions[0] != "api2:b2" {
+		t.Fatalf("expected rollback of step2 only, got %+v", store.deletions)
+	}
+}
+
+func Test_Saga_TemplateError_Rollback1(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{}))
+	defer srv.Close()
 
-    cfg := newCfg(srv.URL)
-    cfg.HTTPTimeoutSeconds = 1
-    env.RegisterWorkflow(SagaWorkflow)
-    registerActivities(env, cfg)
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
 
-    env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
-    if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
-        t.Fatalf("expected workflow timeout/error")
-    }
-    if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
-        t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
-    }
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{
+		Method:   http.MethodPost,
+		Data:     map[string]any{"k": "v"},
+		StepData: map[string]map[string]any{"step2": {"parent": "{{ steps.step3.id }}"}},
+	})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
+		t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
+	}
 }
 
+func Test_Saga_CircuitOpen_FailsFast(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	handlers := defaultHandlers(t, store, map[string]bool{"api2": true}, map[string]time.Duration{})
+	var api2Calls int32
+	fail2 := handlers["/api2/create"]
+	handlers["/api2/create"] = func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&api2Calls, 1)
+		fail2(w, r)
+	}
+	srv := setupServer(t, handlers)
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	cfg.API2.Breaker = configpkg.BreakerConfig{FailureThreshold: 1, OpenSeconds: 60}
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatalf("expected workflow error but got nil")
+	}
+	if n := atomic.LoadInt32(&api2Calls); n != 1 {
+		t.Fatalf("expected open circuit to stop retries after 1 call, got %d", n)
+	}
+	if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
+		t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
+	}
+}
+
+func Test_Saga_ContractViolation_NoRetry_RollbackBoth(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	handlers := defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{})
+	var api2Calls int32
+	create2 := handlers["/api2/create"]
+	handlers["/api2/create"] = func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&api2Calls, 1)
+		create2(w, r)
+	}
+	srv := setupServer(t, handlers)
+	defer srv.Close()
+
+	schema := filepath.Join(t.TempDir(), "api2-create.json")
+	if err := os.WriteFile(schema, []byte(`{"type": "object", "required": ["id", "total"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := newCfg(srv.URL)
+	cfg.API2.Schema = configpkg.SchemaConfig{CreateFile: schema}
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	err := env.GetWorkflowError()
+	if err == nil || !strings.Contains(err.Error(), activities.ContractViolationErrorType) {
+		t.Fatalf("expected contract violation, got %v", err)
+	}
+	if n := atomic.LoadInt32(&api2Calls); n != 1 {
+		t.Fatalf("expected contract violation not to be retried, got %d calls", n)
+	}
+	// The rejected response still created b2, so it is compensated first
+	if len(store.deletions) != 2 || store.deletions[0] != "api2:b2" || store.deletions[1] != "api1:a1" {
+		t.Fatalf("expected rollback of step2 then step1, got %+v", store.deletions)
+	}
+}
+
+func Test_Saga_SlowCall_Heartbeats(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	// Longer than the heartbeat timeout (HTTP timeout / 2) but within the HTTP timeout
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{"api2": 1500 * time.Millisecond}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	var step2Heartbeats int32
+	env.SetOnActivityHeartbeatListener(func(info *activity.Info, _ converter.EncodedValues) {
+		if info.ActivityType.Name == "Step2" {
+			atomic.AddInt32(&step2Heartbeats, 1)
+		}
+	})
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("workflow failed: %v", env.GetWorkflowError())
+	}
+	// One at start and at least one more while waiting (the SDK throttles the rest)
+	if n := atomic.LoadInt32(&step2Heartbeats); n < 2 {
+		t.Fatalf("expected step2 to heartbeat while waiting, got %d heartbeats", n)
+	}
+}
+
+func callbackHandlers(t *testing.T, store *mockStore, tokens chan<- string) map[string]func(http.ResponseWriter, *http.Request) {
+	handlers := defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{})
+	handlers["/api2/create"] = func(w http.ResponseWriter, r *http.Request) {
+		var p activities.RequestPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		url, _ := p.Meta["callback_url"].(string)
+		tokens <- url[strings.LastIndex(url, "/")+1:]
+		w.WriteHeader(http.StatusAccepted)
+	}
+	return handlers
+}
+
+func Test_Saga_AsyncCallback_Completes(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	tokens := make(chan string, 1)
+	srv := setupServer(t, callbackHandlers(t, store, tokens))
+	defer srv.Close()
+
+	// The workflow's config decides which steps wait for a callback, even if
+	// the worker's own config does not enable them.
+	workerCfg := newCfg(srv.URL)
+	workerCfg.CallbackSecret = "callback-secret"
+	cfg := workerCfg
+	cfg.API2.Callback = configpkg.CallbackConfig{Enabled: true, TimeoutSeconds: 60}
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, workerCfg)
+	env.RegisterDelayedCallback(func() {
+		token, err := activities.OpenTaskToken(cfg.CallbackSecret, <-tokens)
+		if err != nil {
+			t.Errorf("open callback token: %v", err)
+		}
+		result, _ := activities.CallbackRequest{ID: "b2-async"}.Outcome()
+		if err := env.CompleteActivity(token, result, nil); err != nil {
+			t.Errorf("complete activity: %v", err)
+		}
+	}, 5*time.Second)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("workflow failed: %v", env.GetWorkflowError())
+	}
+	var out OperationResult
+	_ = env.GetWorkflowResult(&out)
+	if out.Step2ID != "b2-async" || out.Step3ID != "c3" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+}
+
+func Test_Saga_AsyncCallback_Failed_Rollback1(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data map[string]any
+	}{
+		{"no data", nil},
+		// Partner data is not a partial result: naming a resource must not
+		// get it deleted by the compensation.
+		{"partner names a resource", map[string]any{"resource_id": "foreign-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.SetTestTimeout(10 * time.Second)
+			store := &mockStore{}
+			tokens := make(chan string, 1)
+			handlers := callbackHandlers(t, store, tokens)
+			handlers["/api2/foreign-1"] = func(w http.ResponseWriter, r *http.Request) {
+				store.recordDelete("api2:foreign-1")
+			}
+			srv := setupServer(t, handlers)
+			defer srv.Close()
+
+			cfg := newCfg(srv.URL)
+			cfg.API2.Callback = configpkg.CallbackConfig{Enabled: true, TimeoutSeconds: 60}
+			cfg.CallbackSecret = "callback-secret"
+			env.RegisterWorkflow(SagaWorkflow)
+			registerActivities(env, cfg)
+			env.RegisterDelayedCallback(func() {
+				token, err := activities.OpenTaskToken(cfg.CallbackSecret, <-tokens)
+				if err != nil {
+					t.Errorf("open callback token: %v", err)
+				}
+				_, stepErr := activities.CallbackRequest{Status: "failed", Error: "rejected by partner", Data: tc.data}.Outcome()
+				if err := env.CompleteActivity(token, nil, stepErr); err != nil {
+					t.Errorf("complete activity: %v", err)
+				}
+			}, 5*time.Second)
+
+			env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+			if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+				t.Fatalf("expected workflow error but got nil")
+			}
+			if len(store.deletions) != 1 || store.deletions[0] != "api1:a1" {
+				t.Fatalf("expected rollback of step1 only, got %+v", store.deletions)
+			}
+		})
+	}
+}
 
+func scenarioCfg(t *testing.T, scenario string) configpkg.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	if err := os.WriteFile(path, []byte(scenario), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := newCfg("http://unused.invalid")
+	cfg.MockMode = true
+	cfg.MockScenarioFile = path
+	return cfg
+}
+
+func Test_Saga_Scenario_FailTwiceThenSucceed(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	cfg := scenarioCfg(t, `{"api2": {"step2": {"fail_times": 2, "status": 503}}}`)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	var step2Attempts int32
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		if info.ActivityType.Name == "Step2" {
+			atomic.AddInt32(&step2Attempts, 1)
+		}
+	})
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("workflow failed: %v", env.GetWorkflowError())
+	}
+	if n := atomic.LoadInt32(&step2Attempts); n != 3 {
+		t.Fatalf("expected step2 to succeed on attempt 3, got %d attempts", n)
+	}
+}
+
+func Test_Saga_Scenario_FailedCompensation(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	cfg := scenarioCfg(t, `{
+        "api3": {"step3": {"fail_times": -1, "status": 409, "message": "conflict"}},
+        "api2": {"compensate": {"fail_times": -1}}
+    }`)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	var mu sync.Mutex
+	rollbacks := map[string]int{}
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, args converter.EncodedValues) {
+		if info.ActivityType.Name != "RollbackService" {
+			return
+		}
+		var service string
+		_ = args.Get(&service)
+		mu.Lock()
+		rollbacks[service]++
+		mu.Unlock()
+	})
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	err := env.GetWorkflowError()
+	if err == nil || !strings.Contains(err.Error(), "409 conflict") {
+		t.Fatalf("expected step3 scenario failure, got %v", err)
+	}
+	// The failing compensation is retried, and the saga still compensates step 1
+	if rollbacks["api2"] != 3 || rollbacks["api1"] != 1 {
+		t.Fatalf("unexpected compensations: %+v", rollbacks)
+	}
+}
+
+func queryProgress(t *testing.T, env *testsuite.TestWorkflowEnvironment) SagaProgress {
+	t.Helper()
+	v, err := env.QueryWorkflow(ProgressQuery)
+	if err != nil {
+		t.Fatalf("progress query failed: %v", err)
+	}
+	var p SagaProgress
+	if err := v.Get(&p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func Test_Saga_Progress_Completed(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	p := queryProgress(t, env)
+	if p.Status != StatusCompleted || p.Method != http.MethodPost || len(p.Steps) != 3 {
+		t.Fatalf("unexpected progress: %+v", p)
+	}
+	for i, id := range []string{"a1", "b2", "c3"} {
+		if s := p.Steps[i]; s.Status != StatusCompleted || s.ResourceID != id || s.Result["id"] != id {
+			t.Fatalf("unexpected step progress: %+v", s)
+		}
+	}
+}
+
+func Test_Saga_Progress_Compensated(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api3": true}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	p := queryProgress(t, env)
+	if p.Status != StatusCompensated || !strings.Contains(p.FailureCause, "fail3") {
+		t.Fatalf("unexpected progress: %+v", p)
+	}
+	want := []string{StatusCompensated, StatusCompensated, StatusFailed}
+	for i, s := range p.Steps {
+		if s.Status != want[i] {
+			t.Fatalf("step %s: expected %s, got %+v", s.Name, want[i], s)
+		}
+	}
+	if !strings.Contains(p.Steps[2].Error, "fail3") {
+		t.Fatalf("expected step3 error, got %+v", p.Steps[2])
+	}
+}
+
+func Test_Saga_Progress_CompensationFailed(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	cfg := scenarioCfg(t, `{
+        "api3": {"step3": {"fail_times": -1}},
+        "api2": {"compensate": {"fail_times": -1, "message": "delete failed"}}
+    }`)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	p := queryProgress(t, env)
+	if p.Status != StatusFailed {
+		t.Fatalf("expected failed saga, got %+v", p)
+	}
+	if s := p.Steps[1]; s.Status != StatusCompensationFailed || !strings.Contains(s.CompensationError, "delete failed") {
+		t.Fatalf("unexpected step2 progress: %+v", s)
+	}
+	if s := p.Steps[0]; s.Status != StatusCompensated {
+		t.Fatalf("unexpected step1 progress: %+v", s)
+	}
+}
+
+func Test_Saga_SearchAttributes_RecordsFinalStatus(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{"api2": true}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	cfg := newCfg(srv.URL)
+	cfg.SearchAttributes = true
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	env.OnUpsertTypedSearchAttributes(temporal.NewSearchAttributes(StatusAttribute.ValueSet(StatusCompensated))).Return(nil).Once()
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	if env.GetWorkflowError() == nil {
+		t.Fatal("expected workflow error but got nil")
+	}
+	env.AssertExpectations(t)
+}
+
+func Test_Saga_Cancel_CompensatesCompletedSteps(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	// Step 2 is slow enough to be in flight when the saga is cancelled
+	cfg := scenarioCfg(t, `{"api2": {"step2": {"latency_ms": 3000}}}`)
+	env.RegisterWorkflow(SagaWorkflow)
+	registerActivities(env, cfg)
+	var mu sync.Mutex
+	var rollbacks []string
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, args converter.EncodedValues) {
+		if info.ActivityType.Name != "RollbackService" {
+			return
+		}
+		var service string
+		_ = args.Get(&service)
+		mu.Lock()
+		rollbacks = append(rollbacks, service)
+		mu.Unlock()
+	})
+	env.RegisterDelayedCallback(env.CancelWorkflow, 500*time.Millisecond)
+
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}})
+	var canceled *temporal.CanceledError
+	if err := env.GetWorkflowError(); !errors.As(err, &canceled) {
+		t.Fatalf("expected cancelled workflow, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(rollbacks) != 1 || rollbacks[0] != "api1" {
+		t.Fatalf("expected rollback of step1 only, got %+v", rollbacks)
+	}
+	p := queryProgress(t, env)
+	if p.Steps[0].Status != StatusCompensated || p.Steps[1].Status != StatusFailed || p.Steps[2].Status != StatusPending {
+		t.Fatalf("unexpected progress: %+v", p)
+	}
+}
+
+func webhookReceiver(t *testing.T, status int) (*httptest.Server, <-chan CompletionNotice) {
+	t.Helper()
+	notices := make(chan CompletionNotice, 20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n CompletionNotice
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		notices <- n
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, notices
+}
+
+func Test_Saga_CompletionWebhook(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fail   map[string]bool
+		status string
+	}{
+		{"completed", map[string]bool{}, StatusCompleted},
+		{"compensated", map[string]bool{"api3": true}, StatusCompensated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.SetTestTimeout(10 * time.Second)
+			store := &mockStore{}
+			srv := setupServer(t, defaultHandlers(t, store, tc.fail, map[string]time.Duration{}))
+			defer srv.Close()
+			hook, notices := webhookReceiver(t, http.StatusOK)
+
+			cfg := newCfg(srv.URL)
+			cfg.WebhookAllowedHosts = []string{"127.0.0.1"}
+			cfg.WebhookSecrets = map[string]string{"hooks": "s"}
+			env.RegisterWorkflow(SagaWorkflow)
+			env.RegisterWorkflow(CompletionWebhookWorkflow)
+			registerActivities(env, cfg)
+
+			env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}, CallbackURL: hook.URL, CallbackSecretName: "hooks"})
+			if len(notices) != 1 {
+				t.Fatalf("expected one delivery, got %d", len(notices))
+			}
+			n := <-notices
+			if n.Status != tc.status || len(n.Steps) != 3 || (tc.status == StatusCompleted) != (n.Result != nil) {
+				t.Fatalf("unexpected notice: %+v", n)
+			}
+		})
+	}
+}
+
+func Test_Saga_CompletionWebhook_FailureDoesNotAffectOutcome(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	store := &mockStore{}
+	srv := setupServer(t, defaultHandlers(t, store, map[string]bool{}, map[string]time.Duration{}))
+	defer srv.Close()
+
+	hook, notices := webhookReceiver(t, http.StatusBadGateway)
+
+	cfg := newCfg(srv.URL)
+	cfg.WebhookAllowedHosts = []string{"127.0.0.1"}
+	env.RegisterWorkflow(SagaWorkflow)
+	env.RegisterWorkflow(CompletionWebhookWorkflow)
+	registerActivities(env, cfg)
+	// The environment stops with the saga instead of waiting for the
+	// abandoned delivery workflow.
+	env.SetDetachedChildWait(false)
+
+	start := env.Now()
+	env.ExecuteWorkflow(SagaWorkflow, cfg, OperationInput{Method: http.MethodPost, Data: map[string]any{"k": "v"}, CallbackURL: hook.URL})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("webhook failure changed the saga outcome: %v", env.GetWorkflowError())
+	}
+	// Retrying all deliveries would take minutes of backoff.
+	if waited := env.Now().Sub(start); waited >= time.Minute || len(notices) == 10 {
+		t.Fatalf("saga stayed open %s for %d webhook deliveries", waited, len(notices))
+	}
+}
+
+func Test_CompletionWebhookWorkflow_Retries(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(10 * time.Second)
+	hook, notices := webhookReceiver(t, http.StatusBadGateway)
+
+	cfg := newCfg("http://unused.test")
+	cfg.WebhookAllowedHosts = []string{"127.0.0.1"}
+	env.RegisterWorkflow(CompletionWebhookWorkflow)
+	registerActivities(env, cfg)
+
+	env.ExecuteWorkflow(CompletionWebhookWorkflow, cfg, activities.WebhookInput{URL: hook.URL, WorkflowID: "saga-1", Body: []byte(`{}`)})
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() == nil {
+		t.Fatal("expected the undelivered webhook to fail its workflow")
+	}
+	if n := len(notices); n != 10 {
+		t.Fatalf("expected delivery to be retried 10 times, got %d", n)
+	}
+}
@@ -0,0 +1,32 @@
+package workflow
+
+import (
+	"net/http"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Custom search attributes indexing sagas for the list API. They must be
+// registered on the namespace (see the Makefile) before enabling
+// Config.SearchAttributes.
+var (
+	// OperationAttribute is create, update or delete, set when the saga starts.
+	OperationAttribute = temporal.NewSearchAttributeKeyKeyword("SagaOperation")
+	// BusinessKeyAttribute is the caller's business key, set when the saga starts.
+	BusinessKeyAttribute = temporal.NewSearchAttributeKeyKeyword("SagaBusinessKey")
+	// StatusAttribute is the final SagaProgress status, upserted when the saga ends.
+	StatusAttribute = temporal.NewSearchAttributeKeyKeyword("SagaStatus")
+)
+
+// OperationName maps a saga method onto its API operation.
+func OperationName(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return ""
+}
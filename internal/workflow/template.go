@@ -0,0 +1,121 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+)
+
+// stepOutputs holds the results of completed steps keyed by step name
+// ("step1", "step2", ...) so later payloads can reference them.
+type stepOutputs map[string]activities.StepResult
+
+// placeholderRe matches `{{ steps.<step>.<path> }}` references.
+var placeholderRe = regexp.MustCompile(`\{\{\s*steps\.([A-Za-z0-9_]+)\.([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// mergeData returns base with overrides applied on top. Neither map is modified.
+func mergeData(base, overrides map[string]any) map[string]any {
+	if overrides == nil {
+		return base
+	}
+	out := make(map[string]any, len(base)+len(overrides))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
+
+// renderData returns a copy of data with every step reference resolved
+// against outputs. A string that consists of a single reference is replaced
+// by the referenced value as-is (keeping its JSON type); references embedded
+// in a longer string are formatted into it. It is a pure function of its
+// inputs, so it is safe to call from workflow code.
+func renderData(data map[string]any, outputs stepOutputs) (map[string]any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	v, err := renderValue(data, outputs)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+func renderValue(v any, outputs stepOutputs) (any, error) {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			r, err := renderValue(item, outputs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			r, err := renderValue(item, outputs)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = r
+		}
+		return out, nil
+	case string:
+		return renderString(t, outputs)
+	default:
+		return v, nil
+	}
+}
+
+func renderString(s string, outputs stepOutputs) (any, error) {
+	if m := placeholderRe.FindStringSubmatchIndex(s); m != nil && m[0] == 0 && m[1] == len(s) {
+		return lookupStep(outputs, s[m[2]:m[3]], s[m[4]:m[5]])
+	}
+	var firstErr error
+	out := placeholderRe.ReplaceAllStringFunc(s, func(ref string) string {
+		sub := placeholderRe.FindStringSubmatch(ref)
+		v, err := lookupStep(outputs, sub[1], sub[2])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ref
+		}
+		return fmt.Sprint(v)
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+// lookupStep resolves path against a step output. `id` is the step's
+// resource id; any other path is looked up in the decoded response body.
+func lookupStep(outputs stepOutputs, step, path string) (any, error) {
+	res, ok := outputs[step]
+	if !ok {
+		return nil, fmt.Errorf("steps.%s is not available", step)
+	}
+	if path == "id" && res.ResourceID != "" {
+		return res.ResourceID, nil
+	}
+	var cur any = res.Body
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("steps.%s.%s not found", step, path)
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, fmt.Errorf("steps.%s.%s not found", step, path)
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,42 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+)
+
+func Test_RenderData(t *testing.T) {
+	outputs := stepOutputs{
+		"step1": {ResourceID: "a1", Body: map[string]any{"owner": map[string]any{"name": "ann"}, "count": float64(3)}},
+	}
+	data := map[string]any{
+		"parent":  "{{ steps.step1.id }}",
+		"count":   "{{steps.step1.count}}",
+		"label":   "owner={{ steps.step1.owner.name }}",
+		"list":    []any{"{{ steps.step1.id }}", 1},
+		"literal": "plain",
+	}
+	out, err := renderData(data, outputs)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if out["parent"] != "a1" || out["count"] != float64(3) || out["label"] != "owner=ann" || out["literal"] != "plain" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if l := out["list"].([]any); l[0] != "a1" || l[1] != 1 {
+		t.Fatalf("unexpected list: %+v", l)
+	}
+	if data["parent"] != "{{ steps.step1.id }}" {
+		t.Fatalf("input was modified: %+v", data)
+	}
+}
+
+func Test_RenderData_MissingReference(t *testing.T) {
+	outputs := stepOutputs{"step1": activities.StepResult{ResourceID: "a1"}}
+	for _, v := range []string{"{{ steps.step2.id }}", "x-{{ steps.step1.missing }}"} {
+		if _, err := renderData(map[string]any{"k": v}, outputs); err == nil {
+			t.Fatalf("expected error for %q", v)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/AbhinitKumarRai/temporal-saga-workflow/internal/activities"
+	configpkg "github.com/AbhinitKumarRai/temporal-saga-workflow/pkg/config"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// CompletionNotice is the body of completion webhooks.
+type CompletionNotice struct {
+	WorkflowID   string           `json:"workflow_id"`
+	RunID        string           `json:"run_id"`
+	Status       string           `json:"status"`
+	Method       string           `json:"method"`
+	Result       *OperationResult `json:"result,omitempty"`
+	FailureCause string           `json:"failure_cause,omitempty"`
+	Steps        []StepProgress   `json:"steps"`
+	CompletedAt  time.Time        `json:"completed_at"`
+}
+
+// webhookChildChange versions the move of deliveries from an activity of the
+// saga to an abandoned child workflow.
+const webhookChildChange = "webhook-child"
+
+// webhookRetryPolicy retries deliveries for about six minutes of backoff.
+var webhookRetryPolicy = &temporal.RetryPolicy{
+	InitialInterval:    time.Second,
+	BackoffCoefficient: 2,
+	MaximumInterval:    2 * time.Minute,
+	MaximumAttempts:    10,
+}
+
+// notifyCompletion delivers the saga outcome to in.CallbackURL. It runs on a
+// disconnected context so cancelled sagas are reported too. Delivery happens
+// in an abandoned child workflow, so the saga closes as soon as its outcome
+// is known instead of staying open while the webhook is retried; a failed
+// delivery never changes the saga's own outcome.
+func notifyCompletion(ctx workflow.Context, cfg configpkg.Config, in OperationInput, result OperationResult, p SagaProgress, sagaErr error) {
+	ctx, _ = workflow.NewDisconnectedContext(ctx)
+	info := workflow.GetInfo(ctx)
+	notice := CompletionNotice{
+		WorkflowID:   info.WorkflowExecution.ID,
+		RunID:        info.WorkflowExecution.RunID,
+		Status:       p.Status,
+		Method:       p.Method,
+		FailureCause: p.FailureCause,
+		Steps:        p.Steps,
+		CompletedAt:  workflow.Now(ctx).UTC(),
+	}
+	switch {
+	case sagaErr == nil:
+		notice.Result = &result
+	case temporal.IsCanceledError(sagaErr):
+		notice.Status = "canceled"
+	}
+	body, err := json.Marshal(notice)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("failed to encode completion webhook", "error", err)
+		return
+	}
+
+	hook := activities.WebhookInput{
+		URL:        in.CallbackURL,
+		SecretName: in.CallbackSecretName,
+		WorkflowID: notice.WorkflowID,
+		Body:       body,
+	}
+	// Sagas started before the child workflow existed deliver inline.
+	if workflow.GetVersion(ctx, webhookChildChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		if err := deliverWebhook(ctx, cfg, hook); err != nil {
+			workflow.GetLogger(ctx).Warn("completion webhook not delivered", "url", in.CallbackURL, "error", err)
+		}
+		return
+	}
+	// Only the start is awaited: an abandoned child survives its parent.
+	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON})
+	child := workflow.ExecuteChildWorkflow(ctx, CompletionWebhookWorkflow, cfg, hook)
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("completion webhook not started", "url", in.CallbackURL, "error", err)
+	}
+}
+
+// CompletionWebhookWorkflow delivers one completion webhook, retrying it
+// with webhookRetryPolicy.
+func CompletionWebhookWorkflow(ctx workflow.Context, cfg configpkg.Config, hook activities.WebhookInput) error {
+	if err := deliverWebhook(ctx, cfg, hook); err != nil {
+		workflow.GetLogger(ctx).Warn("completion webhook not delivered", "url", hook.URL, "error", err)
+		return err
+	}
+	return nil
+}
+
+func deliverWebhook(ctx workflow.Context, cfg configpkg.Config, hook activities.WebhookInput) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: cfg.HTTPTimeout(),
+		RetryPolicy:         webhookRetryPolicy,
+	})
+	acts := &activities.Activities{Cfg: cfg}
+	return workflow.ExecuteActivity(ctx, acts.NotifyCompletion, hook).Get(ctx, nil)
+}
@@ -7,18 +7,209 @@ import (
 )
 
 type Config struct {
-	TemporalAddress           string        `env:"TEMPORAL_ADDRESS" envDefault:"temporal:7233"`
-	TemporalNamespace         string        `env:"TEMPORAL_NAMESPACE" envDefault:"default"`
-	TemporalTaskQueue         string        `env:"TEMPORAL_TASK_QUEUE" envDefault:"saga-task-queue"`
-	TransactionTimeoutSeconds int           `env:"TRANSACTION_TIMEOUT_SECONDS" envDefault:"30"`
-	API1BaseURL               string        `env:"API1_BASE_URL" envDefault:"https://crudcrud.com/api/4adaea1377ae42358470ccbd5472cf15"`
-	API2BaseURL               string        `env:"API2_BASE_URL" envDefault:"https://crudcrud.com/api/d379fa9d675b4269803fc0f108f5a3eb"`
-	API3BaseURL               string        `env:"API3_BASE_URL" envDefault:"https://crudcrud.com/api/4adaea1377ae42358470ccbd5472cf15"`
-	MockMode                  bool          `env:"MOCK_MODE" envDefault:"true"`
-	HTTPTimeoutSeconds        int           `env:"HTTP_TIMEOUT_SECONDS" envDefault:"10"`
-	ServerPort                string        `env:"SERVER_PORT" envDefault:"8080"`
+	TemporalAddress           string `env:"TEMPORAL_ADDRESS" envDefault:"temporal:7233"`
+	TemporalNamespace         string `env:"TEMPORAL_NAMESPACE" envDefault:"default"`
+	TemporalTaskQueue         string `env:"TEMPORAL_TASK_QUEUE" envDefault:"saga-task-queue"`
+	TransactionTimeoutSeconds int    `env:"TRANSACTION_TIMEOUT_SECONDS" envDefault:"30"`
+	API1BaseURL               string `env:"API1_BASE_URL" envDefault:"https://crudcrud.com/api/4adaea1377ae42358470ccbd5472cf15"`
+	API2BaseURL               string `env:"API2_BASE_URL" envDefault:"https://crudcrud.com/api/d379fa9d675b4269803fc0f108f5a3eb"`
+	API3BaseURL               string `env:"API3_BASE_URL" envDefault:"https://crudcrud.com/api/4adaea1377ae42358470ccbd5472cf15"`
+	MockMode                  bool   `env:"MOCK_MODE" envDefault:"true"`
+	MockScenarioFile          string `env:"MOCK_SCENARIO_FILE"` // fault injection for mock mode, see README
+	HTTPTimeoutSeconds        int    `env:"HTTP_TIMEOUT_SECONDS" envDefault:"10"`
+	ServerPort                string `env:"SERVER_PORT" envDefault:"8080"`
+	ShutdownTimeoutSeconds    int    `env:"SHUTDOWN_TIMEOUT_SECONDS" envDefault:"30"` // grace period for in-flight API requests
+	AdminToken                string `env:"ADMIN_TOKEN" json:"-"`                     // X-Admin-Token for admin endpoints; empty disables them
+	WebhookSecret             string `env:"WEBHOOK_SECRET" json:"-"`                  // signs completion webhooks without their own secret
+	MetricsAddr               string `env:"METRICS_ADDR" envDefault:":9090"`
+	CallbackBaseURL           string `env:"CALLBACK_BASE_URL" envDefault:"http://localhost:8080"`
+	CallbackSecret            string `env:"CALLBACK_SECRET" json:"-"`       // seals task tokens in callback URLs; set on worker and API
+	CassetteMode              string `env:"CASSETTE_MODE" envDefault:"off"` // off, record or replay
+	CassetteDir               string `env:"CASSETTE_DIR" envDefault:"cassettes"`
+	// Completion webhooks: hosts callback_url may point at (empty disables callback_url) and
+	// secrets callers pick by name to sign deliveries, e.g. WEBHOOK_SECRETS=billing:s3cret
+	WebhookAllowedHosts []string          `env:"WEBHOOK_ALLOWED_HOSTS" envSeparator:","`
+	WebhookSecrets      map[string]string `env:"WEBHOOK_SECRETS" envSeparator:"," envKeyValSeparator:":" json:"-"`
+	// Authentication and authorization of API clients, e.g. API_AUTH_API_KEYS=billing:s3cret
+	APIAuth APIAuthConfig `envPrefix:"API_AUTH_" json:"-"`
+	// Body size limit and per-operation data schemas of start requests, e.g. REQUEST_UPDATE_SCHEMA_FILE
+	Requests RequestConfig `envPrefix:"REQUEST_"`
+	// Server behaviour when a start reuses the id of a closed or running saga, see README
+	WorkflowIDReusePolicy    string `env:"WORKFLOW_ID_REUSE_POLICY" envDefault:"allow_duplicate"`
+	WorkflowIDConflictPolicy string `env:"WORKFLOW_ID_CONFLICT_POLICY" envDefault:"fail"`
+	// Index sagas with the SagaOperation/SagaBusinessKey/SagaStatus search attributes (must be registered)
+	SearchAttributes bool `env:"SAGA_SEARCH_ATTRIBUTES" envDefault:"false"`
+	// Worker-wide activity throughput limits (0 = SDK default)
+	WorkerActivitiesPerSecond     float64 `env:"WORKER_TASK_QUEUE_ACTIVITIES_PER_SECOND" envDefault:"0"`
+	WorkerMaxConcurrentActivities int     `env:"WORKER_MAX_CONCURRENT_ACTIVITIES" envDefault:"0"`
+	// Per-service settings, e.g. API1_AUTH_TYPE=bearer
+	API1 ServiceConfig `envPrefix:"API1_"`
+	API2 ServiceConfig `envPrefix:"API2_"`
+	API3 ServiceConfig `envPrefix:"API3_"`
 	// Derived
-	httpTimeout               time.Duration `env:"-"`
+	httpTimeout time.Duration `env:"-"`
+}
+
+// ServiceConfig holds settings for one downstream service.
+type ServiceConfig struct {
+	// Transport is "http" (default), "grpc" or "graphql".
+	Transport string          `env:"TRANSPORT" envDefault:"http"`
+	GRPC      GRPCConfig      `envPrefix:"GRPC_"`
+	GraphQL   GraphQLConfig   `envPrefix:"GRAPHQL_"`
+	Auth      AuthConfig      `envPrefix:"AUTH_"`
+	TLS       TLSConfig       `envPrefix:"TLS_"`
+	HTTP      TransportConfig `envPrefix:"HTTP_"`
+	Breaker   BreakerConfig   `envPrefix:"BREAKER_"`
+	RateLimit RateLimitConfig `envPrefix:"RATE_LIMIT_"`
+	Poll      PollConfig      `envPrefix:"POLL_"`
+	Callback  CallbackConfig  `envPrefix:"CALLBACK_"`
+	Schema    SchemaConfig    `envPrefix:"SCHEMA_"`
+}
+
+// AuthConfig selects how outbound requests to a service are authenticated.
+// Type is one of none, bearer, basic, apikey, oauth2 or hmac. Secrets are
+// excluded from JSON so they never end up in workflow history.
+type AuthConfig struct {
+	Type         string   `env:"TYPE" envDefault:"none"`
+	Token        string   `env:"TOKEN" json:"-"`
+	Username     string   `env:"USERNAME"`
+	Password     string   `env:"PASSWORD" json:"-"`
+	APIKeyHeader string   `env:"API_KEY_HEADER" envDefault:"X-API-Key"`
+	APIKey       string   `env:"API_KEY" json:"-"`
+	TokenURL     string   `env:"TOKEN_URL"`
+	ClientID     string   `env:"CLIENT_ID"`
+	ClientSecret string   `env:"CLIENT_SECRET" json:"-"`
+	Scopes       []string `env:"SCOPES" envSeparator:","`
+	HMACSecret   string   `env:"HMAC_SECRET" json:"-"`
+	HMACHeader   string   `env:"HMAC_HEADER" envDefault:"X-Signature"`
+}
+
+// TLSConfig configures the connection to a service: an optional client
+// certificate for mTLS, a private CA bundle, the expected server name and the
+// minimum TLS version ("1.0" to "1.3").
+type TLSConfig struct {
+	CertFile   string `env:"CERT_FILE"`
+	KeyFile    string `env:"KEY_FILE"`
+	CAFile     string `env:"CA_FILE"`
+	ServerName string `env:"SERVER_NAME"`
+	MinVersion string `env:"MIN_VERSION" envDefault:"1.2"`
+}
+
+// TransportConfig tunes connection pooling for a service's HTTP client.
+type TransportConfig struct {
+	MaxIdleConns           int  `env:"MAX_IDLE_CONNS" envDefault:"100"`
+	MaxIdleConnsPerHost    int  `env:"MAX_IDLE_CONNS_PER_HOST" envDefault:"100"`
+	MaxConnsPerHost        int  `env:"MAX_CONNS_PER_HOST" envDefault:"0"`
+	IdleConnTimeoutSeconds int  `env:"IDLE_CONN_TIMEOUT_SECONDS" envDefault:"90"`
+	KeepAliveSeconds       int  `env:"KEEP_ALIVE_SECONDS" envDefault:"30"`
+	HTTP2                  bool `env:"HTTP2" envDefault:"true"`
+}
+
+// BreakerConfig configures a service's circuit breaker. The breaker opens
+// after FailureThreshold consecutive failures (0 disables it), stays open for
+// OpenSeconds and then lets HalfOpenMaxCalls trial calls through.
+type BreakerConfig struct {
+	FailureThreshold int `env:"FAILURE_THRESHOLD" envDefault:"5"`
+	OpenSeconds      int `env:"OPEN_SECONDS" envDefault:"30"`
+	HalfOpenMaxCalls int `env:"HALF_OPEN_MAX_CALLS" envDefault:"1"`
+}
+
+// RateLimitConfig is a token bucket for calls to a service: RPS requests per
+// second with bursts of up to Burst. An RPS of 0 disables limiting.
+type RateLimitConfig struct {
+	RPS   float64 `env:"RPS" envDefault:"0"`
+	Burst int     `env:"BURST" envDefault:"1"`
+}
+
+// PollConfig controls how asynchronous (202 Accepted) operations are polled:
+// every IntervalSeconds, for up to TimeoutSeconds beyond the HTTP timeout.
+type PollConfig struct {
+	IntervalSeconds int `env:"INTERVAL_SECONDS" envDefault:"2"`
+	TimeoutSeconds  int `env:"TIMEOUT_SECONDS" envDefault:"60"`
+}
+
+// CallbackConfig enables async completion for a service: the step submits
+// its request with a callback URL and waits up to TimeoutSeconds for the
+// partner's webhook before the saga compensates.
+type CallbackConfig struct {
+	Enabled        bool `env:"ENABLED" envDefault:"false"`
+	TimeoutSeconds int  `env:"TIMEOUT_SECONDS" envDefault:"300"`
+}
+
+// SchemaConfig points at JSON Schema files that create (POST) and update
+// (PUT/PATCH) responses of a service must satisfy. Empty paths skip validation.
+type SchemaConfig struct {
+	CreateFile string `env:"CREATE_FILE"`
+	UpdateFile string `env:"UPDATE_FILE"`
+}
+
+// APIAuthConfig authenticates clients of the API with static API keys
+// (client id to key) and/or JWTs verified against a local JWKS file; with
+// neither the API is open. Allow restricts operations (create, update,
+// delete, read, cancel, terminate) to the listed client ids, e.g.
+// "delete=ops,billing;terminate=ops".
+type APIAuthConfig struct {
+	APIKeys        map[string]string `env:"API_KEYS" envSeparator:"," envKeyValSeparator:":"`
+	APIKeyHeader   string            `env:"API_KEY_HEADER" envDefault:"X-API-Key"`
+	JWKSFile       string            `env:"JWKS_FILE"`
+	JWTIssuer      string            `env:"JWT_ISSUER"`
+	JWTAudience    string            `env:"JWT_AUDIENCE"`
+	JWTClientClaim string            `env:"JWT_CLIENT_CLAIM" envDefault:"sub"`
+	Allow          map[string]string `env:"ALLOW" envSeparator:";" envKeyValSeparator:"="`
+}
+
+// RequestConfig bounds start requests to the API. The schema files are JSON
+// Schemas that a request's data must satisfy per operation; empty paths skip
+// the check and a MaxBodyBytes of 0 lifts the size limit.
+type RequestConfig struct {
+	MaxBodyBytes     int64  `env:"MAX_BODY_BYTES" envDefault:"1048576"`
+	CreateSchemaFile string `env:"CREATE_SCHEMA_FILE"`
+	UpdateSchemaFile string `env:"UPDATE_SCHEMA_FILE"`
+	DeleteSchemaFile string `env:"DELETE_SCHEMA_FILE"`
+}
+
+// GRPCConfig describes a service reached over gRPC. Methods maps step
+// methods (POST, PUT, PATCH, GET, DELETE) to full method names such as
+// "orders.v1.Orders/Create"; descriptors come from DescriptorSet (a
+// protoc --include_imports descriptor set) or server reflection.
+type GRPCConfig struct {
+	Target            string            `env:"TARGET"`
+	Methods           map[string]string `env:"METHODS"`
+	CompensateMethod  string            `env:"COMPENSATE_METHOD"`
+	DescriptorSet     string            `env:"DESCRIPTOR_SET"`
+	Reflection        bool              `env:"REFLECTION" envDefault:"false"`
+	IDField           string            `env:"ID_FIELD" envDefault:"id"`
+	CompensateIDField string            `env:"COMPENSATE_ID_FIELD" envDefault:"id"`
+	Plaintext         bool              `env:"PLAINTEXT" envDefault:"false"`
+}
+
+// GraphQLConfig describes a service reached via GraphQL mutations. Mutations
+// maps step methods (POST, PUT, PATCH, GET, DELETE) to mutation documents;
+// documents contain commas and colons, so entries are separated by ";" and
+// keys by "=>". Step data becomes the mutation variables (nested under
+// VariablesKey when set), an existing resource id is passed in IDVariable and
+// the id of a created one is read from IDPath, a dotted path into the
+// response data. Response errors fail the step permanently unless their
+// extensions.code is one of TransientCodes.
+type GraphQLConfig struct {
+	Endpoint             string            `env:"ENDPOINT"`
+	Mutations            map[string]string `env:"MUTATIONS" envSeparator:";" envKeyValSeparator:"=>"`
+	VariablesKey         string            `env:"VARIABLES_KEY"`
+	IDVariable           string            `env:"ID_VARIABLE" envDefault:"id"`
+	IDPath               string            `env:"ID_PATH"`
+	CompensateMutation   string            `env:"COMPENSATE_MUTATION"`
+	CompensateIDVariable string            `env:"COMPENSATE_ID_VARIABLE" envDefault:"id"`
+	TransientCodes       []string          `env:"TRANSIENT_CODES" envSeparator:"," envDefault:"UNAVAILABLE,TIMEOUT,RATE_LIMITED"`
+}
+
+// StepTimeout is the start-to-close timeout for one step on the service:
+// the callback timeout for async-completion services, otherwise the HTTP
+// timeout plus time allowed for polling an async operation.
+func (c Config) StepTimeout(svc ServiceConfig) time.Duration {
+	if svc.Callback.Enabled {
+		return time.Duration(svc.Callback.TimeoutSeconds) * time.Second
+	}
+	return c.HTTPTimeout() + time.Duration(svc.Poll.TimeoutSeconds)*time.Second
 }
 
 func Load() (Config, error) {
@@ -34,6 +225,12 @@ func (c Config) TransactionTimeout() time.Duration {
 	return time.Duration(c.TransactionTimeoutSeconds) * time.Second
 }
 
+// ShutdownTimeout is how long the API server waits for in-flight requests
+// when stopping.
+func (c Config) ShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
+}
+
 func (c *Config) HTTPTimeout() time.Duration {
 	if c.httpTimeout == 0 {
 		c.httpTimeout = time.Duration(c.HTTPTimeoutSeconds) * time.Second
@@ -41,4 +238,28 @@ func (c *Config) HTTPTimeout() time.Duration {
 	return c.httpTimeout
 }
 
+// Service returns the settings for the named service ("api1", "api2", "api3").
+func (c Config) Service(name string) ServiceConfig {
+	switch name {
+	case "api1":
+		return c.API1
+	case "api2":
+		return c.API2
+	case "api3":
+		return c.API3
+	}
+	return ServiceConfig{}
+}
 
+// BaseURL returns the base URL of the named service ("api1", "api2", "api3").
+func (c Config) BaseURL(name string) string {
+	switch name {
+	case "api1":
+		return c.API1BaseURL
+	case "api2":
+		return c.API2BaseURL
+	case "api3":
+		return c.API3BaseURL
+	}
+	return ""
+}
@@ -0,0 +1,351 @@
+// Package sagaclient is a typed Go client for the saga API, following the
+// OpenAPI document it serves at /openapi.json.
+package sagaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartRequest is the body of create, update and delete.
+type StartRequest struct {
+	WorkflowID         string                    `json:"workflow_id,omitempty"`
+	Data               map[string]any            `json:"data,omitempty"`
+	StepData           map[string]map[string]any `json:"step_data,omitempty"`
+	StepMethods        map[string]string         `json:"step_methods,omitempty"`
+	ID1                string                    `json:"id1,omitempty"`
+	ID2                string                    `json:"id2,omitempty"`
+	ID3                string                    `json:"id3,omitempty"`
+	BusinessKey        string                    `json:"business_key,omitempty"`
+	CallbackURL        string                    `json:"callback_url,omitempty"`
+	CallbackSecretName string                    `json:"callback_secret_name,omitempty"`
+}
+
+// StartResponse identifies the started saga. Existing is set when the
+// workflow id was taken and the response is for the saga holding it.
+type StartResponse struct {
+	RunID      string           `json:"run_id"`
+	WorkflowID string           `json:"workflow_id"`
+	Result     *OperationResult `json:"result,omitempty"`
+	Existing   bool             `json:"existing,omitempty"`
+	Status     string           `json:"status,omitempty"`
+}
+
+// OperationResult holds the resource ids of a completed saga.
+type OperationResult struct {
+	Step1ID string `json:"step1_id"`
+	Step2ID string `json:"step2_id"`
+	Step3ID string `json:"step3_id"`
+}
+
+// StepProgress is the state of one saga step.
+type StepProgress struct {
+	Name              string         `json:"name"`
+	Service           string         `json:"service"`
+	Status            string         `json:"status"`
+	ResourceID        string         `json:"resource_id,omitempty"`
+	Result            map[string]any `json:"result,omitempty"`
+	Error             string         `json:"error,omitempty"`
+	CompensationError string         `json:"compensation_error,omitempty"`
+}
+
+// SagaStatus is returned by GET /sagas/{id}.
+type SagaStatus struct {
+	WorkflowID    string           `json:"workflow_id"`
+	RunID         string           `json:"run_id"`
+	Status        string           `json:"status"`
+	Method        string           `json:"method,omitempty"`
+	StartTime     *time.Time       `json:"start_time,omitempty"`
+	CloseTime     *time.Time       `json:"close_time,omitempty"`
+	Result        *OperationResult `json:"result,omitempty"`
+	FailureCause  string           `json:"failure_cause,omitempty"`
+	Steps         []StepProgress   `json:"steps,omitempty"`
+	ProgressError string           `json:"progress_error,omitempty"`
+}
+
+// SagaSummary is one entry of SagaList.
+type SagaSummary struct {
+	WorkflowID  string     `json:"workflow_id"`
+	RunID       string     `json:"run_id"`
+	Status      string     `json:"status"`
+	Operation   string     `json:"operation,omitempty"`
+	BusinessKey string     `json:"business_key,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	CloseTime   *time.Time `json:"close_time,omitempty"`
+}
+
+// SagaList is one page of GET /sagas.
+type SagaList struct {
+	Sagas         []SagaSummary `json:"sagas"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
+
+// ListOptions filters GET /sagas; zero fields are not sent.
+type ListOptions struct {
+	Operation     string
+	Status        string
+	BusinessKey   string
+	From, To      time.Time
+	PageSize      int
+	NextPageToken string
+}
+
+// StepResult is the outcome of a completed step in a SagaEvent.
+type StepResult struct {
+	ResourceID string         `json:"resource_id"`
+	Body       map[string]any `json:"body,omitempty"`
+}
+
+// SagaEvent is the data of one progress event. Name is the SSE event name,
+// e.g. step-completed or saga-failed.
+type SagaEvent struct {
+	Name    string      `json:"-"`
+	ID      int64       `json:"id"`
+	Time    time.Time   `json:"time"`
+	Step    string      `json:"step,omitempty"`
+	Service string      `json:"service,omitempty"`
+	Status  string      `json:"status,omitempty"`
+	Result  *StepResult `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// FieldError is one problem reported with a 422.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is returned for non-2xx responses.
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     []FieldError
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("saga api: %d %s", e.StatusCode, e.Message)
+	for _, f := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", f.Field, f.Message)
+	}
+	return msg
+}
+
+// Client calls the saga API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client; the default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithAPIKey authenticates with a static key sent in X-API-Key, the server's
+// default API_AUTH_API_KEY_HEADER. Use WithHeader for another header.
+func WithAPIKey(key string) Option {
+	return WithHeader("X-API-Key", key)
+}
+
+// WithHeader sends a header with every request, e.g. an API key under a
+// custom API_AUTH_API_KEY_HEADER or X-Admin-Token for cancelling sagas
+// started by other clients.
+func WithHeader(name, value string) Option {
+	return func(c *Client) { c.header.Set(name, value) }
+}
+
+// WithBearerToken authenticates with a JWT.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.header.Set("Authorization", "Bearer "+token) }
+}
+
+// New returns a client for the API at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: http.DefaultClient, header: http.Header{}}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// StartOption configures a create, update or delete call.
+type StartOption func(q url.Values, h http.Header)
+
+// Wait blocks the call until the saga is done and returns its result.
+func Wait() StartOption {
+	return func(q url.Values, _ http.Header) { q.Set("wait", "true") }
+}
+
+// WithIdempotencyKey names the saga so retries of the call return it
+// instead of starting another.
+func WithIdempotencyKey(key string) StartOption {
+	return func(_ url.Values, h http.Header) { h.Set("Idempotency-Key", key) }
+}
+
+// Create starts a saga creating the resource in all three services.
+func (c *Client) Create(ctx context.Context, req StartRequest, opts ...StartOption) (StartResponse, error) {
+	return c.start(ctx, "/create", req, opts)
+}
+
+// Update starts a saga updating the resources req.ID1 to req.ID3.
+func (c *Client) Update(ctx context.Context, req StartRequest, opts ...StartOption) (StartResponse, error) {
+	return c.start(ctx, "/update", req, opts)
+}
+
+// Delete starts a saga deleting the resources req.ID1 to req.ID3.
+func (c *Client) Delete(ctx context.Context, req StartRequest, opts ...StartOption) (StartResponse, error) {
+	return c.start(ctx, "/delete", req, opts)
+}
+
+func (c *Client) start(ctx context.Context, path string, req StartRequest, opts []StartOption) (StartResponse, error) {
+	q, h := url.Values{}, http.Header{}
+	for _, o := range opts {
+		o(q, h)
+	}
+	var resp StartResponse
+	err := c.do(ctx, http.MethodPost, path, q, h, req, &resp)
+	return resp, err
+}
+
+// Get returns the status of a saga.
+func (c *Client) Get(ctx context.Context, workflowID string) (SagaStatus, error) {
+	var resp SagaStatus
+	err := c.do(ctx, http.MethodGet, "/sagas/"+url.PathEscape(workflowID), nil, nil, nil, &resp)
+	return resp, err
+}
+
+// List returns one page of sagas matching opts.
+func (c *Client) List(ctx context.Context, opts ListOptions) (SagaList, error) {
+	q := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	set("operation", opts.Operation)
+	set("status", opts.Status)
+	set("business_key", opts.BusinessKey)
+	if !opts.From.IsZero() {
+		set("from", opts.From.Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		set("to", opts.To.Format(time.RFC3339))
+	}
+	if opts.PageSize > 0 {
+		set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	set("next_page_token", opts.NextPageToken)
+	var resp SagaList
+	err := c.do(ctx, http.MethodGet, "/sagas", q, nil, nil, &resp)
+	return resp, err
+}
+
+// Cancel asks a running saga to stop and compensate its completed steps.
+func (c *Client) Cancel(ctx context.Context, workflowID string) error {
+	return c.do(ctx, http.MethodPost, "/sagas/"+url.PathEscape(workflowID)+"/cancel", nil, nil, nil, nil)
+}
+
+// CancelAndWait cancels a saga and returns its status once compensation is done.
+func (c *Client) CancelAndWait(ctx context.Context, workflowID string) (SagaStatus, error) {
+	var resp SagaStatus
+	err := c.do(ctx, http.MethodPost, "/sagas/"+url.PathEscape(workflowID)+"/cancel", url.Values{"wait": {"true"}}, nil, nil, &resp)
+	return resp, err
+}
+
+// Terminate stops a saga immediately, without compensation. It needs the
+// server's admin token.
+func (c *Client) Terminate(ctx context.Context, workflowID, reason, adminToken string) error {
+	h := http.Header{"X-Admin-Token": {adminToken}}
+	body := map[string]string{"reason": reason}
+	return c.do(ctx, http.MethodPost, "/sagas/"+url.PathEscape(workflowID)+"/terminate", nil, h, body, nil)
+}
+
+// Events streams the progress of a saga to fn, starting after event id
+// since (0 for all), until the saga ends, fn returns an error or ctx is done.
+func (c *Client) Events(ctx context.Context, workflowID string, since int64, fn func(SagaEvent) error) error {
+	h := http.Header{"Accept": {"text/event-stream"}}
+	if since > 0 {
+		h.Set("Last-Event-ID", strconv.FormatInt(since, 10))
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/sagas/"+url.PathEscape(workflowID)+"/events", nil, h, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return readEvents(resp.Body, fn)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, h http.Header, body, out any) error {
+	resp, err := c.send(ctx, method, path, q, h, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send performs the request and turns non-2xx responses into *Error.
+func (c *Client) send(ctx context.Context, method, path string, q url.Values, h http.Header, body any) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	var e struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	if b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20)); json.Unmarshal(b, &e) == nil && e.Error != "" {
+		apiErr.Message, apiErr.Fields = e.Error, e.Fields
+	} else {
+		apiErr.Message = strings.TrimSpace(string(b))
+	}
+	return nil, apiErr
+}
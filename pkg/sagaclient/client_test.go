@@ -0,0 +1,89 @@
+package sagaclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Create_SendsOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req StartRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/create" || r.URL.Query().Get("wait") != "true" || r.Header.Get("Idempotency-Key") != "order-42" ||
+			r.Header.Get("X-API-Key") != "k" || req.BusinessKey != "order-42" {
+			t.Errorf("unexpected request %s %v %+v", r.URL, r.Header, req)
+		}
+		fmt.Fprint(w, `{"workflow_id": "order-42", "run_id": "r1", "existing": true, "status": "completed", "result": {"step1_id": "a1"}}`)
+	}))
+	defer srv.Close()
+
+	resp, err := New(srv.URL+"/", WithAPIKey("k")).Create(context.Background(), StartRequest{BusinessKey: "order-42"}, Wait(), WithIdempotencyKey("order-42"))
+	if err != nil || !resp.Existing || resp.Result == nil || resp.Result.Step1ID != "a1" {
+		t.Fatalf("unexpected response %+v: %v", resp, err)
+	}
+}
+
+func Test_WithHeader_CustomAPIKeyHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Saga-Key") != "k" || r.Header.Get("X-API-Key") != "" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		fmt.Fprint(w, `{"workflow_id": "x", "status": "cancel_requested"}`)
+	}))
+	defer srv.Close()
+
+	if err := New(srv.URL, WithHeader("X-Saga-Key", "k")).Cancel(context.Background(), "x"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sagas/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": "saga not found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "upstream down")
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	var apiErr *Error
+	if _, err := c.Get(context.Background(), "missing"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "saga not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := c.Cancel(context.Background(), "x"); !errors.As(err, &apiErr) || apiErr.Message != "upstream down" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func Test_Events_ResumesAndStopsOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Last-Event-ID") != "5" {
+			t.Errorf("expected Last-Event-ID 5, got %q", r.Header.Get("Last-Event-ID"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id:7\nevent:step-completed\ndata:{\"id\":7,\"time\":\"2025-01-01T00:00:00Z\",\"step\":\"step1\",\"result\":{\"resource_id\":\"a1\"}}\n\n")
+		fmt.Fprint(w, "event:error\ndata:{\"error\":\"history unavailable\"}\n\n")
+	}))
+	defer srv.Close()
+
+	var got []SagaEvent
+	err := New(srv.URL).Events(context.Background(), "saga-1", 5, func(ev SagaEvent) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err == nil || err.Error() != "saga event stream: history unavailable" {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "step-completed" || got[0].ID != 7 || got[0].Result.ResourceID != "a1" {
+		t.Fatalf("unexpected events %+v", got)
+	}
+}
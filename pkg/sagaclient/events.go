@@ -0,0 +1,61 @@
+package sagaclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readEvents parses a server-sent event stream, passing each saga event to
+// fn. An "error" event ends the stream with its message.
+func readEvents(r io.Reader, fn func(SagaEvent) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	var name string
+	var data strings.Builder
+	dispatch := func() error {
+		defer func() { name = ""; data.Reset() }()
+		if data.Len() == 0 {
+			return nil
+		}
+		if name == "error" {
+			var e struct {
+				Error string `json:"error"`
+			}
+			_ = json.Unmarshal([]byte(data.String()), &e)
+			return fmt.Errorf("saga event stream: %s", e.Error)
+		}
+		ev := SagaEvent{Name: name}
+		if err := json.Unmarshal([]byte(data.String()), &ev); err != nil {
+			return fmt.Errorf("decode %s event: %w", name, err)
+		}
+		return fn(ev)
+	}
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return dispatch()
+}